@@ -5,7 +5,10 @@ import (
 	"likha/config"
 	"likha/runner"
 	"likha/util"
+	"os"
+	"time"
 
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +16,13 @@ var (
 	configPath string
 	countStr   string
 	output     string
+	seed       int64
+	workers    int
+	start      int64
+	unordered  bool
+	noTUI      bool
+	progFormat string
+	progEvery  time.Duration
 )
 
 var rootCmd = &cobra.Command{
@@ -32,11 +42,43 @@ It supports a wide range of value generators and allows for complex data structu
 			return fmt.Errorf("invalid count value: %w", err)
 		}
 
+		// Arguments are valid; don't bury runtime errors under the usage text.
+		cmd.SilenceUsage = true
+
 		if output != "" {
+			if len(cfg.Tables) > 0 {
+				return fmt.Errorf("--output cannot be used with tables; set each table's output file instead")
+			}
 			cfg.Output.File = output
 		}
 
-		r, err := runner.NewRunner(cfg, count)
+		if cmd.Flags().Changed("seed") {
+			cfg.Seed = &seed
+		}
+
+		toStdout := runner.IsStdout(cfg.Output.File)
+		for _, t := range cfg.Tables {
+			toStdout = toStdout || runner.IsStdout(t.Output.File)
+		}
+		opts := runner.Options{
+			Workers:   workers,
+			Start:     start,
+			Unordered: unordered,
+			// The TUI needs the terminal to itself, so it is also off when data goes to stdout.
+			Headless:         noTUI || toStdout || !isTerminal(os.Stdout) || !isTerminal(os.Stderr),
+			ProgressFormat:   progFormat,
+			ProgressInterval: progEvery,
+		}
+
+		if len(cfg.Tables) > 0 {
+			s, err := runner.NewSchema(cfg, count, opts)
+			if err != nil {
+				return fmt.Errorf("failed to initialize runner: %w", err)
+			}
+			return s.Run()
+		}
+
+		r, err := runner.NewRunner(cfg, count, opts)
 		if err != nil {
 			return fmt.Errorf("failed to initialize runner: %w", err)
 		}
@@ -48,11 +90,24 @@ It supports a wide range of value generators and allows for complex data structu
 
 func init() {
 	rootCmd.Flags().StringVarP(&configPath, "config", "c", "config.yaml", "Path to the configuration file.")
-	rootCmd.Flags().StringVarP(&countStr, "count", "n", "100", "Number of records to generate (e.g., 10, 10k, 10m, 1b).")
-	rootCmd.Flags().StringVarP(&output, "output", "o", "", "Output file path (overrides config).")
+	rootCmd.Flags().StringVarP(&countStr, "count", "n", "100", "Number of records to generate (e.g., 10, 10k, 10m, 1b); the default for tables without a count.")
+	rootCmd.Flags().StringVarP(&output, "output", "o", "", "Output file path, or - for stdout (overrides config).")
+	rootCmd.Flags().Int64Var(&seed, "seed", 0, "Seed for reproducible output (overrides config).")
+	rootCmd.Flags().IntVarP(&workers, "workers", "w", 0, "Number of generator workers (default: number of CPUs).")
+	rootCmd.Flags().Int64Var(&start, "start", 0, "Index of the first record to generate.")
+	rootCmd.Flags().BoolVar(&noTUI, "no-tui", false, "Print progress lines to stderr instead of the interactive progress bar.")
+	rootCmd.Flags().StringVar(&progFormat, "progress-format", "plain", "Progress line format without the TUI: plain or json.")
+	rootCmd.Flags().DurationVar(&progEvery, "progress-interval", time.Second, "Delay between progress lines without the TUI.")
+	rootCmd.Flags().BoolVar(&unordered, "unordered", false, "Write rows as soon as they are ready instead of in index order (faster).")
 	// Removed the --progress flag as it's no longer needed
 }
 
+// isTerminal reports whether f is attached to a terminal. The TUI is only
+// usable when both stdout and stderr are.
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
 // Execute executes the root command.
 func Execute() error {
 	return rootCmd.Execute()
@@ -3,28 +3,102 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the main configuration structure.
 type Config struct {
-	Fields []Field      `yaml:"fields"`
-	Output OutputConfig `yaml:"output"`
+	Seed     *int64       `yaml:"seed"`   // Optional; a random seed is chosen when unset
+	Locale   string       `yaml:"locale"` // Default locale for fake-data functions and entities
+	Entities []Entity     `yaml:"entities"`
+	Fields   []Field      `yaml:"fields"`
+	Output   OutputConfig `yaml:"output"`
+	Tables   []Table      `yaml:"tables"` // Replaces Entities and Fields for multi-table schemas
+}
+
+// Table is one dataset of a multi-table schema, with its own record count and
+// output. Its fields can reference the keys of other tables with 'ref'.
+// A child table names a parent instead of a count: each parent record gets
+// its own child records, which can read the parent's fields.
+type Table struct {
+	Name      string                 `yaml:"name"`
+	Count     string                 `yaml:"count"`      // e.g. "10k"; defaults to the --count flag
+	Seed      *int64                 `yaml:"seed"`       // Optional; overrides the seed derived from Config.Seed
+	Parent    string                 `yaml:"parent"`     // Parent table of a child table
+	PerParent map[string]interface{} `yaml:"per_parent"` // Child records per parent record: min, max and an optional distribution
+	Entities  []Entity               `yaml:"entities"`
+	Fields    []Field                `yaml:"fields"`
+	Output    OutputConfig           `yaml:"output"` // Defaults to the top-level output type and settings, in <name>.<type>
+}
+
+// Entity is a composite value, such as a person or an address, generated once
+// per record. Fields project its attributes with 'from'.
+type Entity struct {
+	Name     string                 `yaml:"name"`
+	Type     string                 `yaml:"type"` // person, address or correlated
+	Seed     *int64                 `yaml:"seed"`
+	Settings map[string]interface{} `yaml:"settings"`
 }
 
 // Field represents a single data field to be generated.
 type Field struct {
 	Name      string          `yaml:"name"`
+	Seed      *int64          `yaml:"seed"` // Optional; overrides the seed derived from Config.Seed
 	Generator GeneratorConfig `yaml:"generator"`
+	From      string          `yaml:"from"` // Entity attribute to project, e.g. "address.city"; replaces the generator
+	Ref       string          `yaml:"ref"`  // Key of another table, e.g. "users.id"; replaces the generator
+
+	// Nesting: an object holds child fields; an array holds min_items to
+	// max_items values from the generator or, if fields are given, objects.
+	// Any other type converts the generated value, e.g. to int or decimal.
+	Type     string  `yaml:"type"` // object, array or a value type; empty to keep the generated value
+	Fields   []Field `yaml:"fields"`
+	MinItems int     `yaml:"min_items"`
+	MaxItems int     `yaml:"max_items"`
+	Scale    *int    `yaml:"scale"` // Decimal places for type decimal
+
+	// Share of records (0-1) that get null or an empty string instead of a generated value.
+	NullRate  float64 `yaml:"null_rate"`
+	EmptyRate float64 `yaml:"empty_rate"`
+
+	// Uniqueness constraint
+	Unique                  bool    `yaml:"unique"`
+	UniqueRetries           int     `yaml:"unique_retries"`             // Regeneration attempts per record before failing (default 100)
+	UniqueMode              string  `yaml:"unique_mode"`                // exact (default), hash or disk
+	UniqueFalsePositiveRate float64 `yaml:"unique_false_positive_rate"` // Of the disk mode's Bloom filter (default 0.001)
 }
 
 // GeneratorConfig holds the configuration for a value generator.
 type GeneratorConfig struct {
-	Type        string                          `yaml:"type"`
-	Settings    map[string]interface{}          `yaml:"settings"`
-	SourceField string                          `yaml:"source_field"` // For foreignkey
-	Map         map[interface{}]GeneratorConfig `yaml:"map"`          // For foreignkey
+	Type        string                           `yaml:"type"`
+	Settings    map[string]interface{}           `yaml:"settings"`
+	SourceField string                           `yaml:"source_field"` // For foreignkey
+	Map         map[interface{}]GeneratorChoices `yaml:"map"`          // For foreignkey
+	Weight      *float64                         `yaml:"weight"`       // Relative weight within a GeneratorChoices list; 1 when unset
+}
+
+// GeneratorChoices is a foreignkey map entry: either a single generator, or a
+// list of generators of which one is picked per record according to its weight.
+type GeneratorChoices []GeneratorConfig
+
+// UnmarshalYAML accepts both a single generator mapping and a list of them.
+func (c *GeneratorChoices) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		var list []GeneratorConfig
+		if err := value.Decode(&list); err != nil {
+			return err
+		}
+		*c = list
+		return nil
+	}
+	var single GeneratorConfig
+	if err := value.Decode(&single); err != nil {
+		return err
+	}
+	*c = GeneratorChoices{single}
+	return nil
 }
 
 // OutputConfig defines the output format and its settings.
@@ -47,5 +121,77 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("could not unmarshal config file %s: %w", path, err)
 	}
 
+	if cfg.Locale != "" {
+		setDefaultLocale(cfg.Fields, cfg.Entities, cfg.Locale)
+		for _, t := range cfg.Tables {
+			setDefaultLocale(t.Fields, t.Entities, cfg.Locale)
+		}
+	}
+
+	dir := filepath.Dir(path)
+	resolvePaths(cfg.Fields, dir)
+	for _, t := range cfg.Tables {
+		resolvePaths(t.Fields, dir)
+	}
+
 	return &cfg, nil
 }
+
+// resolvePaths makes the file paths in generator settings relative to the
+// directory of the config file rather than the working directory.
+func resolvePaths(fields []Field, dir string) {
+	for i := range fields {
+		fields[i].Generator.resolvePaths(dir)
+		resolvePaths(fields[i].Fields, dir)
+	}
+}
+
+// resolvePaths joins a relative text 'corpus' setting to dir, including
+// those nested in foreignkey maps.
+func (g *GeneratorConfig) resolvePaths(dir string) {
+	if g.Type == "text" {
+		if p, ok := g.Settings["corpus"].(string); ok && p != "" && !filepath.IsAbs(p) {
+			g.Settings["corpus"] = filepath.Join(dir, p)
+		}
+	}
+	for _, choices := range g.Map {
+		for i := range choices {
+			choices[i].resolvePaths(dir)
+		}
+	}
+}
+
+// setDefaultLocale sets the 'locale' setting of fields and entities that do not have one.
+func setDefaultLocale(fields []Field, entities []Entity, locale string) {
+	for i := range fields {
+		fields[i].Generator.setDefaultLocale(locale)
+		setDefaultLocale(fields[i].Fields, nil, locale)
+	}
+	for i := range entities {
+		e := &entities[i]
+		if e.Settings == nil {
+			e.Settings = make(map[string]interface{})
+		}
+		if _, ok := e.Settings["locale"]; !ok {
+			e.Settings["locale"] = locale
+		}
+	}
+}
+
+// setDefaultLocale sets the 'locale' setting of builtin generators that do not have one,
+// including those nested in foreignkey maps.
+func (g *GeneratorConfig) setDefaultLocale(locale string) {
+	if g.Type == "builtin" {
+		if g.Settings == nil {
+			g.Settings = make(map[string]interface{})
+		}
+		if _, ok := g.Settings["locale"]; !ok {
+			g.Settings["locale"] = locale
+		}
+	}
+	for _, choices := range g.Map {
+		for i := range choices {
+			choices[i].setDefaultLocale(locale)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigResolvesCorpus(t *testing.T) {
+	dir := t.TempDir()
+	abs := filepath.Join(t.TempDir(), "abs.txt")
+	path := filepath.Join(dir, "config.yaml")
+	yaml := `
+fields:
+  - name: "post"
+    generator:
+      type: "text"
+      settings: {model: "markov", corpus: "corpus/posts.txt"}
+  - name: "reply"
+    generator:
+      type: "foreignkey"
+      source_field: "post"
+      map:
+        a:
+          - type: "text"
+            settings: {model: "markov", corpus: "` + abs + `"}
+tables:
+  - name: "comments"
+    fields:
+      - name: "body"
+        generator:
+          type: "text"
+          settings: {model: "markov", corpus: "../comments.txt"}
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		got  interface{}
+		want string
+	}{
+		{cfg.Fields[0].Generator.Settings["corpus"], filepath.Join(dir, "corpus", "posts.txt")},
+		{cfg.Fields[1].Generator.Map["a"][0].Settings["corpus"], abs},
+		{cfg.Tables[0].Fields[0].Generator.Settings["corpus"], filepath.Join(filepath.Dir(dir), "comments.txt")},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("corpus is %v, want %s", tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package distribution
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"likha/util"
+)
+
+// Params holds the named parameters of a distribution. Missing parameters take defaults
+// derived from the min/max range.
+type Params map[string]float64
+
+// paramNames lists the parameters of each distribution in positional order,
+// as used by expression functions such as $random_int(1, 100, "normal", 50, 10).
+var paramNames = map[string][]string{
+	"uniform":     {},
+	"normal":      {"mean", "stddev"},
+	"lognormal":   {"mean", "stddev"},
+	"exponential": {"lambda"},
+	"poisson":     {"lambda"},
+	"zipf":        {"skew"},
+	"pareto":      {"alpha"},
+	"beta":        {"alpha", "beta"},
+}
+
+// ParamNames returns the parameter names of a distribution in positional order.
+func ParamNames(kind string) ([]string, error) {
+	names, ok := paramNames[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown distribution '%s' (expected uniform, normal, lognormal, exponential, poisson, zipf, pareto or beta)", kind)
+	}
+	return names, nil
+}
+
+// Sampler draws numbers from a distribution, clamped to [min, max].
+// It is immutable and safe for concurrent use.
+type Sampler struct {
+	kind     string
+	min, max float64
+	a, b     float64 // Distribution parameters; meaning depends on kind
+	zipf     *zipf   // For the zipf kind
+}
+
+// New creates a Sampler. Defaults:
+//   - normal: mean at the middle of the range, stddev a sixth of the range
+//   - lognormal: same mean/stddev defaults, describing the values themselves
+//   - exponential: values start at min, lambda defaults to 4/(max-min)
+//   - poisson: lambda defaults to the middle of the range
+//   - zipf: ranks start at min, skew defaults to 1.5 (must be > 1)
+//   - pareto: values start at min (or 1 if min <= 0), alpha defaults to 1.16 (the 80/20 rule)
+//   - beta: scaled onto [min, max], alpha and beta default to 2
+func New(kind string, params Params, min, max float64) (*Sampler, error) {
+	if min > max {
+		return nil, fmt.Errorf("min cannot be greater than max")
+	}
+	if _, err := ParamNames(kind); err != nil {
+		return nil, err
+	}
+	s := &Sampler{kind: kind, min: min, max: max}
+	get := func(name string, def float64) float64 {
+		if v, ok := params[name]; ok {
+			return v
+		}
+		return def
+	}
+	span := max - min
+
+	switch kind {
+	case "normal", "lognormal":
+		s.a = get("mean", min+span/2)
+		s.b = get("stddev", span/6)
+		if s.b < 0 {
+			return nil, fmt.Errorf("%s stddev must not be negative", kind)
+		}
+		if kind == "lognormal" {
+			if s.a <= 0 || s.b == 0 {
+				return nil, fmt.Errorf("lognormal mean and stddev must be positive")
+			}
+			// Convert the mean/stddev of the values to those of the underlying normal.
+			sigma2 := math.Log(1 + (s.b*s.b)/(s.a*s.a))
+			s.a, s.b = math.Log(s.a)-sigma2/2, math.Sqrt(sigma2)
+		}
+	case "exponential":
+		def := 1.0
+		if span > 0 {
+			def = 4 / span
+		}
+		s.a = get("lambda", def)
+		if s.a <= 0 {
+			return nil, fmt.Errorf("exponential lambda must be positive")
+		}
+	case "poisson":
+		s.a = get("lambda", min+span/2)
+		if s.a <= 0 {
+			return nil, fmt.Errorf("poisson lambda must be positive")
+		}
+	case "zipf":
+		s.a = get("skew", 1.5)
+		if s.a <= 1 {
+			return nil, fmt.Errorf("zipf skew must be greater than 1")
+		}
+		s.zipf = newZipf(s.a, math.Max(math.Floor(span), 0))
+	case "pareto":
+		s.a = get("alpha", 1.16)
+		s.b = min
+		if s.b <= 0 {
+			s.b = 1
+		}
+		if s.a <= 0 {
+			return nil, fmt.Errorf("pareto alpha must be positive")
+		}
+	case "beta":
+		s.a = get("alpha", 2)
+		s.b = get("beta", 2)
+		if s.a <= 0 || s.b <= 0 {
+			return nil, fmt.Errorf("beta alpha and beta must be positive")
+		}
+	}
+	return s, nil
+}
+
+// FromSettings creates the Sampler for the optional 'distribution' setting,
+// reading its parameters from settings of the same name. It returns nil for
+// the default uniform draw.
+func FromSettings(settings map[string]interface{}, min, max float64) (*Sampler, error) {
+	kind, ok := settings["distribution"].(string)
+	if !ok || kind == "uniform" {
+		return nil, nil
+	}
+	names, err := ParamNames(kind)
+	if err != nil {
+		return nil, err
+	}
+	params := Params{}
+	for _, name := range names {
+		if v, ok := settings[name]; ok {
+			f, ok := util.InterfaceToFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("'%s' must be a number", name)
+			}
+			params[name] = f
+		}
+	}
+	return New(kind, params, min, max)
+}
+
+// Sample draws a value, clamped to [min, max].
+func (s *Sampler) Sample(r *rand.Rand) float64 {
+	var v float64
+	switch s.kind {
+	case "normal":
+		v = s.a + r.NormFloat64()*s.b
+	case "lognormal":
+		v = math.Exp(s.a + r.NormFloat64()*s.b)
+	case "exponential":
+		v = s.min + r.ExpFloat64()/s.a
+	case "poisson":
+		v = float64(poisson(r, s.a))
+	case "zipf":
+		v = s.min + s.zipf.sample(r)
+	case "pareto":
+		v = s.b / math.Pow(1-r.Float64(), 1/s.a)
+	case "beta":
+		x := gamma(r, s.a)
+		y := gamma(r, s.b)
+		v = s.min + (s.max-s.min)*x/(x+y)
+	default: // uniform
+		v = s.min + r.Float64()*(s.max-s.min)
+	}
+	return math.Min(math.Max(v, s.min), s.max)
+}
+
+// zipf draws ranks 0 to imax with probability proportional to (rank+1)^-q,
+// by Hörmann and Derflinger's rejection-inversion. It is math/rand's Zipf
+// with v = 1, but its constants are computed once rather than per sample.
+type zipf struct {
+	q     float64
+	hxm   float64 // h(imax + 0.5)
+	width float64 // Width of the range of h that is sampled
+	s     float64 // Acceptance shortcut
+}
+
+func newZipf(q, imax float64) *zipf {
+	z := &zipf{q: q}
+	z.hxm = z.h(imax + 0.5)
+	z.width = z.h(0.5) - 1 - z.hxm
+	z.s = 1 - z.hinv(z.h(1.5)-math.Exp(-q*math.Ln2))
+	return z
+}
+
+// h is an integral of the unnormalized density (1+x)^-q, and hinv its inverse.
+func (z *zipf) h(x float64) float64 {
+	return math.Exp((1-z.q)*math.Log(1+x)) / (1 - z.q)
+}
+
+func (z *zipf) hinv(x float64) float64 {
+	return math.Exp(math.Log((1-z.q)*x)/(1-z.q)) - 1
+}
+
+func (z *zipf) sample(r *rand.Rand) float64 {
+	for {
+		ur := z.hxm + r.Float64()*z.width
+		x := z.hinv(ur)
+		k := math.Floor(x + 0.5)
+		if k-x <= z.s || ur >= z.h(k+0.5)-math.Exp(-z.q*math.Log(k+1)) {
+			return k
+		}
+	}
+}
+
+// poisson draws a Poisson-distributed count: Knuth's multiplication method
+// for small lambda, Hörmann's transformed rejection (PTRS) for large lambda.
+func poisson(r *rand.Rand, lambda float64) int64 {
+	if lambda < 30 {
+		limit := math.Exp(-lambda)
+		var k int64
+		for p := r.Float64(); p > limit; p *= r.Float64() {
+			k++
+		}
+		return k
+	}
+
+	slam := math.Sqrt(lambda)
+	loglam := math.Log(lambda)
+	b := 0.931 + 2.53*slam
+	a := -0.059 + 0.02483*b
+	invalpha := 1.1239 + 1.1328/(b-3.4)
+	vr := 0.9277 - 3.6224/(b-2)
+	for {
+		u := r.Float64() - 0.5
+		v := r.Float64()
+		us := 0.5 - math.Abs(u)
+		k := math.Floor((2*a/us+b)*u + lambda + 0.43)
+		if us >= 0.07 && v <= vr {
+			return int64(k)
+		}
+		if k < 0 || (us < 0.013 && v > us) {
+			continue
+		}
+		lg, _ := math.Lgamma(k + 1)
+		if math.Log(v)+math.Log(invalpha)-math.Log(a/(us*us)+b) <= -lambda+k*loglam-lg {
+			return int64(k)
+		}
+	}
+}
+
+// gamma draws from a Gamma(shape, 1) distribution using Marsaglia and Tsang's method.
+func gamma(r *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		// Boost the shape and correct with a power of a uniform variate.
+		return gamma(r, shape+1) * math.Pow(r.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := r.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := r.Float64()
+		if u < 1-0.0331*x*x*x*x || math.Log(u) < 0.5*x*x+d*(1-v+math.Log(v)) {
+			return d * v
+		}
+	}
+}
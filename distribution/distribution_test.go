@@ -0,0 +1,125 @@
+package distribution
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestSampleMeans(t *testing.T) {
+	tests := []struct {
+		kind     string
+		params   Params
+		min, max float64
+		mean     float64
+	}{
+		{"uniform", nil, 10, 20, 15},
+		{"normal", nil, 0, 60, 30},
+		{"normal", Params{"mean": 5, "stddev": 2}, -100, 100, 5},
+		{"lognormal", Params{"mean": 50, "stddev": 20}, 0, 1e9, 50},
+		{"exponential", Params{"lambda": 0.5}, 3, 1e9, 5},
+		{"poisson", Params{"lambda": 4}, 0, 1e9, 4},
+		{"poisson", Params{"lambda": 120}, 0, 1e9, 120},
+		{"pareto", Params{"alpha": 3}, 2, 1e9, 3},
+		{"beta", Params{"alpha": 2, "beta": 6}, 0, 100, 25},
+		// P(rank) is proportional to (rank+1)^-3 over ranks 0 and 1: 8/9 and 1/9.
+		{"zipf", Params{"skew": 3}, 1, 2, 1 + 1.0/9},
+	}
+	for _, tt := range tests {
+		s, err := New(tt.kind, tt.params, tt.min, tt.max)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.kind, err)
+		}
+		r := rand.New(rand.NewPCG(1, 2))
+		const n = 50000
+		var sum float64
+		for i := 0; i < n; i++ {
+			sum += s.Sample(r)
+		}
+		if got := sum / n; math.Abs(got-tt.mean) > 0.03*math.Max(math.Abs(tt.mean), 1) {
+			t.Errorf("%s %v: mean %.3f, expected %.3f", tt.kind, tt.params, got, tt.mean)
+		}
+	}
+}
+
+func TestSampleClamps(t *testing.T) {
+	tests := []struct {
+		kind   string
+		params Params
+	}{
+		{"normal", Params{"mean": 0, "stddev": 100}},
+		{"lognormal", Params{"mean": 10, "stddev": 50}},
+		{"exponential", Params{"lambda": 0.01}},
+		{"poisson", Params{"lambda": 50}},
+		{"zipf", Params{"skew": 1.1}},
+		{"pareto", Params{"alpha": 0.5}},
+		{"beta", Params{"alpha": 0.5, "beta": 0.5}},
+		{"uniform", nil},
+	}
+	for _, tt := range tests {
+		s, err := New(tt.kind, tt.params, 5, 15)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.kind, err)
+		}
+		r := rand.New(rand.NewPCG(3, 4))
+		for i := 0; i < 10000; i++ {
+			if v := s.Sample(r); v < 5 || v > 15 {
+				t.Fatalf("%s: sample %v is outside [5, 15]", tt.kind, v)
+			}
+		}
+	}
+}
+
+func TestZipfMatchesMathRand(t *testing.T) {
+	s, err := New("zipf", Params{"skew": 1.5}, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1, r2 := rand.New(rand.NewPCG(5, 6)), rand.New(rand.NewPCG(5, 6))
+	z := rand.NewZipf(r2, 1.5, 1, 1000)
+	for i := 0; i < 10000; i++ {
+		if got, want := s.Sample(r1), float64(z.Uint64()); got != want {
+			t.Fatalf("sample %d: got rank %v, math/rand gives %v", i, got, want)
+		}
+	}
+}
+
+func TestNewRejectsBadParameters(t *testing.T) {
+	tests := []struct {
+		kind     string
+		params   Params
+		min, max float64
+	}{
+		{"normal", nil, 10, 1},
+		{"normal", Params{"stddev": -1}, 0, 10},
+		{"lognormal", Params{"mean": 0}, 0, 10},
+		{"lognormal", Params{"stddev": 0}, 0, 10},
+		{"exponential", Params{"lambda": 0}, 0, 10},
+		{"poisson", Params{"lambda": -2}, 0, 10},
+		{"zipf", Params{"skew": 1}, 0, 10},
+		{"pareto", Params{"alpha": 0}, 0, 10},
+		{"beta", Params{"beta": 0}, 0, 10},
+		{"gaussian", nil, 0, 10},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.kind, tt.params, tt.min, tt.max); err == nil {
+			t.Errorf("New(%s, %v, %v, %v) should fail", tt.kind, tt.params, tt.min, tt.max)
+		}
+	}
+}
+
+func TestFromSettings(t *testing.T) {
+	s, err := FromSettings(map[string]interface{}{"distribution": "normal", "mean": 3, "stddev": 0}, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Sample(rand.New(rand.NewPCG(1, 1))); v != 3 {
+		t.Fatalf("got %v, expected the mean with a stddev of 0", v)
+	}
+	if s, err := FromSettings(map[string]interface{}{}, 0, 10); s != nil || err != nil {
+		t.Fatalf("expected no sampler for a uniform draw, got %v, %v", s, err)
+	}
+	if _, err := FromSettings(map[string]interface{}{"distribution": "normal", "mean": "high"}, 0, 10); err == nil {
+		t.Fatal("expected an error for a non-numeric parameter")
+	}
+}
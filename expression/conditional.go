@@ -0,0 +1,117 @@
+package expression
+
+import "fmt"
+
+// Conditionals are special forms: their arguments are evaluated lazily, so
+// only the branch that is taken runs, and draws from the random source only
+// when it is taken.
+
+// conditional is cond ? then : else, also written if(cond, then, else).
+type conditional struct {
+	cond, then, otherwise node
+}
+
+func (n *conditional) eval(e *env) (interface{}, error) {
+	c, err := n.cond.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(c) {
+		return n.then.eval(e)
+	}
+	return n.otherwise.eval(e)
+}
+
+// caseNode is case(cond1, result1, cond2, result2, ..., default): the result
+// of the first true condition, else the default or null.
+type caseNode struct {
+	conds, results []node
+	otherwise      node
+}
+
+func (n *caseNode) eval(e *env) (interface{}, error) {
+	for i, cond := range n.conds {
+		c, err := cond.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(c) {
+			return n.results[i].eval(e)
+		}
+	}
+	return n.otherwise.eval(e)
+}
+
+// switchNode is switch(value, match1, result1, match2, result2, ..., default):
+// the result for the first match equal to value, else the default or null.
+type switchNode struct {
+	subject          node
+	matches, results []node
+	otherwise        node
+}
+
+func (n *switchNode) eval(e *env) (interface{}, error) {
+	v, err := n.subject.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	for i, match := range n.matches {
+		m, err := match.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		if equal(v, m) {
+			return n.results[i].eval(e)
+		}
+	}
+	return n.otherwise.eval(e)
+}
+
+// specialForms build the nodes of functions whose arguments are not all evaluated.
+var specialForms = map[string]func(args []node) (node, error){
+	"if":     newIf,
+	"case":   newCase,
+	"switch": newSwitch,
+}
+
+func newIf(args []node) (node, error) {
+	if len(args) != 2 && len(args) != 3 {
+		return nil, fmt.Errorf("if takes 2 or 3 arguments, got %d", len(args))
+	}
+	n := &conditional{cond: args[0], then: args[1], otherwise: &literal{}}
+	if len(args) == 3 {
+		n.otherwise = args[2]
+	}
+	return n, nil
+}
+
+func newCase(args []node) (node, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("case takes at least 2 arguments, got %d", len(args))
+	}
+	n := &caseNode{}
+	n.conds, n.results, n.otherwise = pairs(args)
+	return n, nil
+}
+
+func newSwitch(args []node) (node, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("switch takes at least 3 arguments, got %d", len(args))
+	}
+	n := &switchNode{subject: args[0]}
+	n.matches, n.results, n.otherwise = pairs(args[1:])
+	return n, nil
+}
+
+// pairs splits key, result, key, result, ... [default] arguments. The default is null when omitted.
+func pairs(args []node) (keys, results []node, otherwise node) {
+	for i := 0; i+1 < len(args); i += 2 {
+		keys = append(keys, args[i])
+		results = append(results, args[i+1])
+	}
+	otherwise = &literal{}
+	if len(args)%2 == 1 {
+		otherwise = args[len(args)-1]
+	}
+	return keys, results, otherwise
+}
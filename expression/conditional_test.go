@@ -0,0 +1,112 @@
+package expression
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"likha/generator/types"
+	"likha/value"
+)
+
+func TestConditionals(t *testing.T) {
+	row := map[string]interface{}{"qty": int64(12), "tier": "gold", "note": ""}
+	checkValues(t, []valueTest{
+		{"${#qty > 10 ? 'bulk' : 'single'}", row, "bulk"},
+		{"${#qty > 20 ? 'bulk' : 'single'}", row, "single"},
+		{"${#qty > 20 ? 'a' : #qty > 10 ? 'b' : 'c'}", row, "b"},
+		{"${#note ? #note : 'none'}", row, "none"},
+		{"${(#qty > 10 ? 2 : 1) * 3}", row, int64(6)},
+		{"$if(#qty > 10, 'bulk', 'single')", row, "bulk"},
+		{"$if(#qty > 20, 'bulk')", row, nil},
+		{"${if(#tier == 'gold', #qty * 2, #qty)}", row, int64(24)},
+		{"$case(#qty < 5, 'small', #qty < 20, 'medium', 'large')", row, "medium"},
+		{"$case(#qty < 5, 'small', #qty < 10, 'medium', 'large')", row, "large"},
+		{"$case(#qty < 5, 'small', #qty < 10, 'medium')", row, nil},
+		{"$switch(#tier, 'silver', 0.05, 'gold', 0.1, 0)", row, 0.1},
+		{"$switch(#tier, 'silver', 0.05, 'bronze', 0.02, 0)", row, int64(0)},
+		{"$switch(#tier, 'silver', 0.05, 'bronze', 0.02)", row, nil},
+		{"$switch(#qty, '12', 'numeric match')", row, "numeric match"},
+	})
+}
+
+func TestConditionalArity(t *testing.T) {
+	for _, src := range []string{"$if(true)", "$if(1, 2, 3, 4)", "$case(true)", "$switch(1, 2)", "${true ? 1}"} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("%s should not compile", src)
+		}
+	}
+}
+
+// TestLazyBranches checks that the branch not taken neither fails nor draws
+// from the random source, so adding a condition does not shift later values.
+func TestLazyBranches(t *testing.T) {
+	row := map[string]interface{}{"x": int64(1)}
+	for _, src := range []string{
+		"${#x == 1 ? 'one' : random_int(2, 1)}",
+		"${#x != 1 ? 1 / 0 : 'one'}",
+		"$if(#x == 1, 'one', $random_string(-1))",
+		"$if(#x == 1, 'one', $random_int(1, 100))",
+		"$case(#x == 1, 'one', #x / 0 > 1, $random_int(1, 100))",
+		"$case(#x == 2, $random_int(1, 100), 'one')",
+		"$switch(#x, 1, 'one', 1 / 0, $uuid())",
+		"$switch(#x, 2, $random_int(1, 100), 'one')",
+		"${#x == 1 || random_int(2, 1) ? 'one' : 'two'}",
+		"${#x == 2 && random_int(2, 1) ? 'two' : 'one'}",
+	} {
+		tmpl, err := Compile(src)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		r := rand.New(rand.NewPCG(9, 9))
+		got, err := tmpl.Value(&types.Context{Rand: r}, row)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if got != "one" && got != true {
+			t.Errorf("%s = %#v, want \"one\"", src, got)
+		}
+		if next, want := r.Uint64(), rand.New(rand.NewPCG(9, 9)).Uint64(); next != want {
+			t.Errorf("%s drew from the random source in a branch that was not taken", src)
+		}
+	}
+
+	// The branch that is taken draws as usual.
+	tmpl, err := Compile("$if(#x == 1, $random_int(1, 100), 0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewPCG(9, 9))
+	if _, err := tmpl.Value(&types.Context{Rand: r}, row); err != nil {
+		t.Fatal(err)
+	}
+	if r.Uint64() == rand.New(rand.NewPCG(9, 9)).Uint64() {
+		t.Error("the branch taken did not draw from the random source")
+	}
+}
+
+// TestValueTypes checks the types Template.Value keeps for single
+// expressions over int, float and decimal fields.
+func TestValueTypes(t *testing.T) {
+	qty, price, discount := int64(3), 19.99, 0.1
+	row := map[string]interface{}{
+		"quantity":   qty,
+		"unit_price": value.NewDecimal(price, 2),
+		"discount":   discount,
+		"count":      int32(4),
+		"active":     true,
+	}
+	checkValues(t, []valueTest{
+		{"${#quantity * #unit_price * (1 - #discount)}", row, float64(qty) * price * (1 - discount)},
+		{"${#quantity * #count}", row, int64(12)},
+		{"${#quantity + #count / 2}", row, 5.0},
+		{"${#quantity % 2}", row, int64(1)},
+		{"${#quantity * 1.5}", row, 4.5},
+		{"${#quantity > 2 && #active}", row, true},
+		{"${#discount == 0.1}", row, true},
+		{"${#quantity + ' units'}", row, "3 units"},
+		{"#unit_price", row, value.NewDecimal(price, 2)},
+		{"#quantity units", row, "3 units"},
+		{"${null}", row, nil},
+	})
+}
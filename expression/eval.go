@@ -0,0 +1,366 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"likha/generator/types"
+	"likha/value"
+)
+
+// env is the state an expression is evaluated against.
+type env struct {
+	row map[string]interface{}
+	ctx *types.Context
+}
+
+// node is a compiled part of an expression.
+type node interface {
+	eval(e *env) (interface{}, error)
+}
+
+type literal struct {
+	val interface{}
+}
+
+func (n *literal) eval(e *env) (interface{}, error) { return n.val, nil }
+
+// fieldRef reads a field of the current row. A path such as #address.city
+// or #parent.order_date reads into a map: a field, an entity or, in a child
+// table, the parent record. In template text, a reference that does not
+// resolve is kept as written, so text such as "Order #1" is left alone;
+// inside an expression it is null.
+type fieldRef struct {
+	name   string
+	path   []string // Keys below the field, for #name.key
+	text   string   // Original text, e.g. "#name"
+	inExpr bool
+}
+
+// newFieldRef creates the reference for path, the text after '#'.
+func newFieldRef(path string, inExpr bool) *fieldRef {
+	keys := strings.Split(path, ".")
+	return &fieldRef{name: keys[0], path: keys[1:], text: "#" + path, inExpr: inExpr}
+}
+
+func (n *fieldRef) eval(e *env) (interface{}, error) {
+	v, ok := e.row[n.name]
+	if !ok && len(n.path) > 0 {
+		// Entities and the parent record are kept under their row key.
+		v, ok = e.row[types.RowKey(n.name)]
+	}
+	if !ok {
+		if n.inExpr {
+			return nil, nil
+		}
+		return n.text, nil
+	}
+	for i, key := range n.path {
+		next, found := member(v, key)
+		if !found {
+			if n.inExpr {
+				return nil, nil
+			}
+			// Not a path after all, e.g. "#domain.com": keep the rest as text.
+			return format(v) + "." + strings.Join(n.path[i:], "."), nil
+		}
+		v = next
+	}
+	return normalize(v), nil
+}
+
+// member returns the value under key in a map, or at index key in a list.
+func member(v interface{}, key string) (interface{}, bool) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		m, ok := x[key]
+		return m, ok
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(x) {
+			return nil, false
+		}
+		return x[i], true
+	}
+	return nil, false
+}
+
+type unary struct {
+	op string
+	x  node
+}
+
+func (n *unary) eval(e *env) (interface{}, error) {
+	v, err := n.x.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "!" {
+		return !truthy(v), nil
+	}
+	if v == nil {
+		return nil, nil
+	}
+	num, ok := toNumber(v)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %s", describe(v))
+	}
+	if i, ok := num.(int64); ok && i != math.MinInt64 {
+		return -i, nil
+	}
+	return -toFloat(num), nil
+}
+
+type binary struct {
+	op          string
+	left, right node
+}
+
+func (n *binary) eval(e *env) (interface{}, error) {
+	l, err := n.left.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	// Logical operators short-circuit.
+	switch n.op {
+	case "&&":
+		if !truthy(l) {
+			return false, nil
+		}
+		r, err := n.right.eval(e)
+		return truthy(r), err
+	case "||":
+		if truthy(l) {
+			return true, nil
+		}
+		r, err := n.right.eval(e)
+		return truthy(r), err
+	}
+
+	r, err := n.right.eval(e)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==", "!=":
+		eq := equal(l, r)
+		return eq == (n.op == "=="), nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(l, r)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	case "+":
+		_, ls := l.(string)
+		_, rs := r.(string)
+		if ls || rs {
+			return format(l) + format(r), nil
+		}
+	}
+	return arithmetic(n.op, l, r)
+}
+
+// arithmetic applies +, -, *, / or % to two numbers. Numeric strings are
+// converted; null in, null out. / always divides exactly.
+func arithmetic(op string, l, r interface{}) (interface{}, error) {
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	ln, ok := toNumber(l)
+	if !ok {
+		return nil, fmt.Errorf("operator %s needs numbers, got %s", op, describe(l))
+	}
+	rn, ok := toNumber(r)
+	if !ok {
+		return nil, fmt.Errorf("operator %s needs numbers, got %s", op, describe(r))
+	}
+
+	// Integer results that would overflow int64 fall through to floats.
+	li, lInt := ln.(int64)
+	ri, rInt := rn.(int64)
+	if lInt && rInt {
+		switch op {
+		case "+":
+			if sum := li + ri; (sum > li) == (ri > 0) {
+				return sum, nil
+			}
+		case "-":
+			if diff := li - ri; (diff < li) == (ri > 0) {
+				return diff, nil
+			}
+		case "*":
+			if li == 0 || ri == 0 {
+				return int64(0), nil
+			}
+			if p := li * ri; p/ri == li && !(li == -1 && ri == math.MinInt64) && !(ri == -1 && li == math.MinInt64) {
+				return p, nil
+			}
+		case "%":
+			if ri == 0 {
+				return nil, fmt.Errorf("modulo by zero")
+			}
+			return li % ri, nil
+		}
+	}
+
+	lf, rf := toFloat(ln), toFloat(rn)
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	}
+	if rf == 0 {
+		return nil, fmt.Errorf("modulo by zero")
+	}
+	return math.Mod(lf, rf), nil
+}
+
+// equal compares two values, numerically when both are numbers or numeric strings.
+func equal(l, r interface{}) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	if c, err := compare(l, r); err == nil {
+		return c == 0
+	}
+	return format(l) == format(r)
+}
+
+// compare orders two values: strings alphabetically, otherwise numerically.
+func compare(l, r interface{}) (int, error) {
+	ls, lStr := l.(string)
+	rs, rStr := r.(string)
+	if lStr && rStr {
+		return strings.Compare(ls, rs), nil
+	}
+	ln, lok := toNumber(l)
+	rn, rok := toNumber(r)
+	if !lok || !rok {
+		return 0, fmt.Errorf("cannot compare %s with %s", describe(l), describe(r))
+	}
+	lf, rf := toFloat(ln), toFloat(rn)
+	switch {
+	case lf < rf:
+		return -1, nil
+	case lf > rf:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// normalize converts field values to the types expressions work with:
+// int64, float64, bool, string and nil. Other types pass through.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint:
+		if uint64(x) > math.MaxInt64 {
+			return float64(x)
+		}
+		return int64(x)
+	case uint8:
+		return int64(x)
+	case uint16:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case uint64:
+		if x > math.MaxInt64 {
+			return float64(x)
+		}
+		return int64(x)
+	case float32:
+		return float64(x)
+	}
+	return v
+}
+
+// toNumber returns v as an int64 or float64, parsing numeric strings.
+func toNumber(v interface{}) (interface{}, bool) {
+	switch x := normalize(v).(type) {
+	case int64, float64:
+		return x, true
+	case value.Decimal:
+		return x.Float, true
+	case string:
+		s := strings.TrimSpace(x)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// toFloat converts the result of toNumber to a float64.
+func toFloat(num interface{}) float64 {
+	if i, ok := num.(int64); ok {
+		return float64(i)
+	}
+	return num.(float64)
+}
+
+// truthy reports whether a value counts as true: anything but false, null, 0 and "".
+func truthy(v interface{}) bool {
+	switch x := normalize(v).(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case int64:
+		return x != 0
+	case float64:
+		return x != 0
+	case value.Decimal:
+		return x.Float != 0
+	case string:
+		return x != ""
+	}
+	return true
+}
+
+// format renders a value as template text. Null renders as nothing.
+func format(v interface{}) string {
+	return value.Text(v)
+}
+
+// describe names a value's type for error messages.
+func describe(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return "bool"
+	}
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,56 @@
+package expression
+
+import (
+	"strings"
+
+	"likha/generator/types"
+)
+
+// Template is a compiled expression template: literal text mixed with field
+// references (#name), function calls ($name(args)) and expressions (${...}).
+// It is parsed once and is safe for concurrent use. Values substituted into
+// the text are never parsed again, so a field holding "$uuid()" stays as is.
+type Template struct {
+	source string
+	parts  []node
+}
+
+// Compile parses a template, reporting syntax errors, unknown functions and
+// wrong numbers of arguments.
+func Compile(template string) (*Template, error) {
+	parts, err := parse(template)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{source: template, parts: parts}, nil
+}
+
+// Value evaluates the template for a row. A template that is a single call,
+// field reference or ${...} expression keeps the type of its result: int64,
+// float64, bool, string or nil. Anything else is rendered as a string.
+func (t *Template) Value(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	if len(t.parts) == 1 {
+		return t.parts[0].eval(&env{row: row, ctx: ctx})
+	}
+	return t.Evaluate(ctx, row)
+}
+
+// Evaluate renders the template for a row as text, drawing all randomness
+// from ctx.Rand.
+func (t *Template) Evaluate(ctx *types.Context, row map[string]interface{}) (string, error) {
+	e := &env{row: row, ctx: ctx}
+	var b strings.Builder
+	for _, p := range t.parts {
+		v, err := p.eval(e)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(format(v))
+	}
+	return b.String(), nil
+}
+
+// String returns the template source.
+func (t *Template) String() string {
+	return t.source
+}
@@ -0,0 +1,205 @@
+package expression
+
+import (
+	"math"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"likha/generator/types"
+)
+
+// valueTest is a template, the row it is evaluated against and its expected value.
+type valueTest struct {
+	template string
+	row      map[string]interface{}
+	want     interface{}
+}
+
+// checkValues compiles and evaluates each template with Value.
+func checkValues(t *testing.T, tests []valueTest) {
+	t.Helper()
+	ctx := &types.Context{Rand: rand.New(rand.NewPCG(1, 1))}
+	for _, tt := range tests {
+		tmpl, err := Compile(tt.template)
+		if err != nil {
+			t.Errorf("%s: %v", tt.template, err)
+			continue
+		}
+		got, err := tmpl.Value(ctx, tt.row)
+		if err != nil {
+			t.Errorf("%s: %v", tt.template, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	checkValues(t, []valueTest{
+		{"${1 + 2 * 3}", nil, int64(7)},
+		{"${(1 + 2) * 3}", nil, int64(9)},
+		{"${10 - 4 - 3}", nil, int64(3)},
+		{"${2 * (3 + (4 - 1)) % 5}", nil, int64(2)},
+		{"${7 / 2}", nil, 3.5},
+		{"${-2 * 3}", nil, int64(-6)},
+		{"${--2}", nil, int64(2)},
+		{"${1 + 2 == 3}", nil, true},
+		{"${1 < 2 == 2 < 1}", nil, false},
+		{"${!0 && 1 < 2 || false}", nil, true},
+		{"${false || 0 && 1}", nil, false},
+		{`${"a" + 1 + 2}`, nil, "a12"},
+		{`${1 + 2 + "a"}`, nil, "3a"},
+		{`${"10" * 2}`, nil, int64(20)},
+		{"${#n + 1}", map[string]interface{}{"n": nil}, nil},
+		{"${#n * 2}", map[string]interface{}{"n": uint64(math.MaxUint64)}, float64(math.MaxUint64) * 2},
+	})
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	checkValues(t, []valueTest{
+		{"${9223372036854775807 + 1}", nil, 9223372036854775808.0},
+		{"${-9223372036854775807 - 2}", nil, -9223372036854775809.0},
+		{"${4611686018427387904 * 2}", nil, 9223372036854775808.0},
+		{"${-1 * (-9223372036854775807 - 1)}", nil, 9223372036854775808.0},
+		{"${-(-9223372036854775807 - 1)}", nil, 9223372036854775808.0},
+		{"${9223372036854775807 - 1}", nil, int64(math.MaxInt64 - 1)},
+		{"${-4611686018427387904 * 2}", nil, int64(math.MinInt64)},
+		{"${0 * -9223372036854775807}", nil, int64(0)},
+	})
+}
+
+func TestTemplates(t *testing.T) {
+	row := map[string]interface{}{
+		"id":   int64(21),
+		"note": "$random_int(1,2)",
+		"user": map[string]interface{}{"name": "Ada"},
+	}
+	checkValues(t, []valueTest{
+		{"##tag costs $$5", nil, "#tag costs $5"},
+		{"$$upper(x) and ##id", row, "$upper(x) and #id"},
+		{"Price in $USD", nil, "Price in $USD"},
+		{"ID-#id-${#id * 2}", row, "ID-21-42"},
+		{"#id", row, int64(21)},
+		{"#user.name!", row, "Ada!"},
+		{"#missing", row, "#missing"},
+		// Field values are inserted as they are, never evaluated.
+		{"#note", row, "$random_int(1,2)"},
+		{"note: #note", row, "note: $random_int(1,2)"},
+		{"${#note + '!'}", row, "$random_int(1,2)!"},
+		{`$upper($substr("hello", 1, 3))`, nil, "ELL"},
+		{`$len($concat("ab", $pad("7", 3, "0")))`, nil, int64(5)},
+		{`${upper(lower("AbC") + "d")}`, nil, "ABCD"},
+		{`$concat("a,b", "(c)")`, nil, "a,b(c)"},
+		{`$replace("x)y", ")", ",")`, nil, "x,y"},
+		{`$concat('it\'s ', "\"hi\"")`, nil, `it's "hi"`},
+		{`$upper("a") and $lower("B")`, nil, "A and b"},
+	})
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{`$upper("a"`, "expected ',' or ')'"},
+		{`$concat("a", `, "expected a value"},
+		{`$upper("a)`, "unterminated"},
+		{`$nope(1)`, "unknown expression function: nope"},
+		{`$upper("a", "b")`, "argument"},
+		{"${1 +}", "expected a value"},
+		{"${(1 + 2}", "expected ')'"},
+		{"${1 + 2", "expected '}'"},
+		{"${1 ? 2}", "expected ':'"},
+		{"${upper}", "unknown name 'upper'"},
+	}
+	for _, tt := range tests {
+		if _, err := Compile(tt.template); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.template, tt.want, err)
+		}
+	}
+}
+
+func TestRandomFunctions(t *testing.T) {
+	ctx := &types.Context{Rand: rand.New(rand.NewPCG(2, 3))}
+	wide, err := Compile("$random_int(-9223372036854775808, 9223372036854775807)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var negative, positive bool
+	for i := 0; i < 200; i++ {
+		v, err := wide.Value(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		negative = negative || v.(int64) < 0
+		positive = positive || v.(int64) > 0
+	}
+	if !negative || !positive {
+		t.Errorf("the full int64 range gave only one sign")
+	}
+
+	checkValues(t, []valueTest{
+		{"$random_int(5, 5)", nil, int64(5)},
+		{"$random_int(9223372036854775807, 9223372036854775807)", nil, int64(math.MaxInt64)},
+		{"$random_epoch(-9223372036854775808, -9223372036854775808)", nil, int64(math.MinInt64)},
+		{`$random_string(4, "é")`, nil, "éééé"},
+		{"$random_string(0)", nil, ""},
+	})
+
+	for _, src := range []string{
+		"$random_string(-1)",
+		`$random_string(3, "")`,
+		"$random_int(2, 1)",
+		"$random_int(0, 9223372036854775808)",
+	} {
+		tmpl, err := Compile(src)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if v, err := tmpl.Value(ctx, nil); err == nil {
+			t.Errorf("%s = %#v, expected an error", src, v)
+		}
+	}
+}
+
+func TestTimeOrderedIDs(t *testing.T) {
+	for _, src := range []string{
+		`$uuid_v7("2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z")`,
+		`$ulid("2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z", "1s")`,
+		"$ulid()",
+	} {
+		tmpl, err := Compile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := types.NewContext()
+		ids := make([]string, 500)
+		for i := range ids {
+			ctx.Reset(int64(i), 7)
+			v, err := tmpl.Value(ctx, nil)
+			if err != nil {
+				t.Fatalf("%s: %v", src, err)
+			}
+			ids[i] = v.(string)
+		}
+		if !slices.IsSorted(ids) || len(slices.Compact(slices.Clone(ids))) != len(ids) {
+			t.Errorf("%s values do not increase with the index", src)
+		}
+	}
+
+	tmpl, err := Compile(`$uuid_v7("2024-01-01T00:00:00Z", "2024-01-01T00:00:01Z", "1s")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := types.NewContext()
+	ctx.Reset(2, 7)
+	if _, err := tmpl.Value(ctx, nil); err == nil {
+		t.Error("a record after the end of the range should fail")
+	}
+}
@@ -0,0 +1,383 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"likha/distribution"
+	"likha/generator/types"
+	"likha/regexgen"
+	"likha/util"
+	"likha/uuid"
+	"likha/value"
+)
+
+// function is an expression function. Arguments arrive evaluated; a call
+// with fewer or more arguments than minArgs/maxArgs (-1 for no limit) is
+// rejected when the template is compiled.
+type function struct {
+	minArgs, maxArgs int
+	call             func(ctx *types.Context, args []interface{}) (interface{}, error)
+}
+
+// functions is the registry of expression functions.
+var functions = map[string]*function{
+	"random_int":     {2, -1, randomInt},
+	"random_decimal": {0, -1, randomDecimal},
+	"random_string":  {0, 2, randomString},
+	"random_epoch":   {0, 2, randomEpoch},
+	"random_isodate": {0, 2, randomISODate},
+	"uuid":           {0, 0, uuidV4},
+	"uuid_v4":        {0, 0, uuidV4},
+	"uuid_v7":        {0, 3, uuidV7},
+	"ulid":           {0, 3, ulid},
+	"uuid_v5":        {2, 2, uuidV5},
+	"regex":          {1, 2, regex},
+
+	"upper":       {1, 1, upper},
+	"lower":       {1, 1, lower},
+	"slug":        {1, 1, slug},
+	"substr":      {2, 3, substr},
+	"pad":         {2, 4, pad},
+	"replace":     {3, 3, replace},
+	"concat":      {0, -1, concat},
+	"format_date": {2, 2, formatDate},
+	"date_add":    {3, 3, dateAdd},
+	"hash_md5":    {1, 1, hashMD5},
+	"sha256":      {1, 1, hashSHA256},
+	"hash_sha256": {1, 1, hashSHA256},
+	"base64":      {1, 1, base64Encode},
+	"round":       {1, 2, round},
+	"abs":         {1, 1, abs},
+	"len":         {1, 1, length},
+}
+
+// call is a compiled function call.
+type call struct {
+	name string
+	fn   *function
+	args []node
+}
+
+// newCall resolves a function and checks its number of arguments.
+func newCall(name string, args []node) (node, error) {
+	if form, ok := specialForms[name]; ok {
+		return form(args)
+	}
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown expression function: %s", name)
+	}
+	if len(args) < fn.minArgs || fn.maxArgs >= 0 && len(args) > fn.maxArgs {
+		want := fmt.Sprintf("%d to %d", fn.minArgs, fn.maxArgs)
+		switch {
+		case fn.maxArgs < 0:
+			want = fmt.Sprintf("at least %d", fn.minArgs)
+		case fn.minArgs == fn.maxArgs:
+			want = fmt.Sprintf("%d", fn.minArgs)
+		}
+		return nil, fmt.Errorf("%s takes %s arguments, got %d", name, want, len(args))
+	}
+	return &call{name: name, fn: fn, args: args}, nil
+}
+
+func (n *call) eval(e *env) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(e.ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+// arg returns argument i, or nil if it was not given.
+func arg(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+// intArg reads argument i as an integer, defaulting to def when it is absent or null.
+func intArg(args []interface{}, i int, name string, def int64) (int64, error) {
+	v := arg(args, i)
+	if v == nil {
+		return def, nil
+	}
+	num, ok := toNumber(v)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number, got %s", name, describe(v))
+	}
+	if n, ok := num.(int64); ok {
+		return n, nil
+	}
+	f := num.(float64)
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("%s must be an integer, got %v", name, f)
+	}
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("%s is outside the range of int, got %v", name, f)
+	}
+	return int64(f), nil
+}
+
+// floatArg reads argument i as a number, defaulting to def when it is absent or null.
+func floatArg(args []interface{}, i int, name string, def float64) (float64, error) {
+	v := arg(args, i)
+	if v == nil {
+		return def, nil
+	}
+	num, ok := toNumber(v)
+	if !ok {
+		return 0, fmt.Errorf("%s must be a number, got %s", name, describe(v))
+	}
+	return toFloat(num), nil
+}
+
+// started is when the program started. Default time ranges end there, so
+// they are the same for every record.
+var started = time.Now()
+
+// timeArgs reads optional RFC 3339 start/end arguments, defaulting to the year before the program started.
+func timeArgs(args []interface{}) (time.Time, time.Time, error) {
+	start := started.Add(-365 * 24 * time.Hour)
+	end := started
+	if v := arg(args, 0); v != nil && v != "" {
+		s, err := time.Parse(time.RFC3339, format(v))
+		if err != nil {
+			return start, end, fmt.Errorf("invalid start time: %w", err)
+		}
+		start = s
+	}
+	if v := arg(args, 1); v != nil && v != "" {
+		e, err := time.Parse(time.RFC3339, format(v))
+		if err != nil {
+			return start, end, fmt.Errorf("invalid end time: %w", err)
+		}
+		end = e
+	}
+	if start.After(end) {
+		return start, end, fmt.Errorf("start cannot be after end")
+	}
+	return start, end, nil
+}
+
+// distributionArgs builds a sampler from trailing function arguments: the
+// distribution name followed by its parameters in positional order, e.g.
+// "normal", 50, 10. It returns nil when no distribution is given.
+func distributionArgs(args []interface{}, min, max float64) (*distribution.Sampler, error) {
+	if len(args) == 0 || args[0] == nil || args[0] == "" {
+		return nil, nil
+	}
+	kind := format(args[0])
+	names, err := distribution.ParamNames(kind)
+	if err != nil {
+		return nil, err
+	}
+	if len(args)-1 > len(names) {
+		return nil, fmt.Errorf("%s takes at most %d parameters, got %d", kind, len(names), len(args)-1)
+	}
+	params := distribution.Params{}
+	for i := range args[1:] {
+		f, err := floatArg(args[1:], i, kind+" "+names[i], 0)
+		if err != nil {
+			return nil, err
+		}
+		params[names[i]] = f
+	}
+	return distribution.New(kind, params, min, max)
+}
+
+// randomInt implements random_int(min, max[, distribution, params...]).
+func randomInt(ctx *types.Context, args []interface{}) (interface{}, error) {
+	min, err := intArg(args, 0, "min", 0)
+	if err != nil {
+		return nil, err
+	}
+	max, err := intArg(args, 1, "max", 0)
+	if err != nil {
+		return nil, err
+	}
+	if min > max {
+		return nil, fmt.Errorf("min cannot be greater than max")
+	}
+	sampler, err := distributionArgs(args[2:], float64(min), float64(max))
+	if err != nil {
+		return nil, err
+	}
+	if sampler != nil {
+		return int64(math.Round(sampler.Sample(ctx.Rand))), nil
+	}
+	return randomRange(ctx.Rand, min, max), nil
+}
+
+// randomRange draws an integer from [min, max], which may span all of int64.
+func randomRange(r *rand.Rand, min, max int64) int64 {
+	span := uint64(max) - uint64(min)
+	if span == math.MaxUint64 {
+		return int64(r.Uint64())
+	}
+	return int64(uint64(min) + r.Uint64N(span+1))
+}
+
+// randomDecimal implements random_decimal([min[, max[, places[, distribution, params...]]]]).
+func randomDecimal(ctx *types.Context, args []interface{}) (interface{}, error) {
+	min, err := floatArg(args, 0, "min", 0)
+	if err != nil {
+		return nil, err
+	}
+	max, err := floatArg(args, 1, "max", 100)
+	if err != nil {
+		return nil, err
+	}
+	places, err := intArg(args, 2, "places", 2)
+	if err != nil {
+		return nil, err
+	}
+	if min > max {
+		return nil, fmt.Errorf("min cannot be greater than max")
+	}
+	var sampler *distribution.Sampler
+	if len(args) > 3 {
+		sampler, err = distributionArgs(args[3:], min, max)
+		if err != nil {
+			return nil, err
+		}
+	}
+	val := min + ctx.Rand.Float64()*(max-min)
+	if sampler != nil {
+		val = sampler.Sample(ctx.Rand)
+	}
+	return value.NewDecimal(val, int(places)), nil
+}
+
+// randomString implements random_string([length[, charset]]).
+func randomString(ctx *types.Context, args []interface{}) (interface{}, error) {
+	length, err := intArg(args, 0, "length", 10)
+	if err != nil {
+		return nil, err
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("length must not be negative, got %d", length)
+	}
+	charset := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+	if v := arg(args, 1); v != nil {
+		if charset = []rune(format(v)); len(charset) == 0 {
+			return nil, fmt.Errorf("charset must not be empty")
+		}
+	}
+	b := make([]rune, length)
+	for i := range b {
+		b[i] = charset[ctx.Rand.IntN(len(charset))]
+	}
+	return string(b), nil
+}
+
+// randomEpoch implements random_epoch([start[, end]]) in Unix seconds.
+func randomEpoch(ctx *types.Context, args []interface{}) (interface{}, error) {
+	start, err := intArg(args, 0, "start", time.Now().Add(-365*24*time.Hour).Unix())
+	if err != nil {
+		return nil, err
+	}
+	end, err := intArg(args, 1, "end", time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	if start > end {
+		return nil, fmt.Errorf("start cannot be after end")
+	}
+	return randomRange(ctx.Rand, start, end), nil
+}
+
+// randomISODate implements random_isodate([start[, end]]).
+func randomISODate(ctx *types.Context, args []interface{}) (interface{}, error) {
+	start, end, err := timeArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if end.Unix() == start.Unix() {
+		return time.Unix(start.Unix(), 0), nil
+	}
+	sec := ctx.Rand.Int64N(end.Unix()-start.Unix()) + start.Unix()
+	return time.Unix(sec, 0), nil
+}
+
+func uuidV4(ctx *types.Context, args []interface{}) (interface{}, error) {
+	return uuid.NewV4(ctx.Rand).String(), nil
+}
+
+// uuidV7 implements uuid_v7([start[, end[, step]]]). Record i is stamped
+// start + i*step (step defaults to 1ms), so the IDs follow the record order.
+func uuidV7(ctx *types.Context, args []interface{}) (interface{}, error) {
+	t, err := indexTime(ctx.Index, args)
+	if err != nil {
+		return nil, err
+	}
+	return uuid.NewV7(ctx.Rand, t).String(), nil
+}
+
+// ulid implements ulid([start[, end[, step]]]), stamped like uuid_v7.
+func ulid(ctx *types.Context, args []interface{}) (interface{}, error) {
+	t, err := indexTime(ctx.Index, args)
+	if err != nil {
+		return nil, err
+	}
+	return uuid.NewULID(ctx.Rand, t), nil
+}
+
+// indexTime reads the start, end and step arguments of a time-ordered ID and
+// returns the timestamp of the record at index.
+func indexTime(index int64, args []interface{}) (time.Time, error) {
+	start, end, err := timeArgs(args)
+	if err != nil {
+		return time.Time{}, err
+	}
+	step := time.Millisecond
+	if v := arg(args, 2); v != nil {
+		if step, err = util.ParseDuration(format(v)); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return uuid.IndexMillis(index, start, end, step)
+}
+
+// uuidV5 implements uuid_v5(namespace, name).
+func uuidV5(ctx *types.Context, args []interface{}) (interface{}, error) {
+	ns, err := uuid.ParseNamespace(format(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	return uuid.NewV5(ns, format(args[1])).String(), nil
+}
+
+// regexCache holds compiled patterns, keyed by max_repeat and pattern.
+var regexCache sync.Map
+
+// regex implements regex(pattern[, max_repeat]).
+func regex(ctx *types.Context, args []interface{}) (interface{}, error) {
+	pattern := format(args[0])
+	maxRepeat, err := intArg(args, 1, "max_repeat", regexgen.DefaultMaxRepeat)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%d:%s", maxRepeat, pattern)
+	gen, ok := regexCache.Load(key)
+	if !ok {
+		compiled, err := regexgen.Compile(pattern, int(maxRepeat))
+		if err != nil {
+			return nil, err
+		}
+		gen, _ = regexCache.LoadOrStore(key, compiled)
+	}
+	return gen.(*regexgen.Generator).Generate(ctx.Rand), nil
+}
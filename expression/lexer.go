@@ -0,0 +1,169 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind identifies the kind of a token.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokString
+	tokIdent  // Function name or keyword (true, false, null)
+	tokField  // #name or #name.path
+	tokDollar // $name, a function name in template syntax
+	tokOp     // Operators and punctuation
+)
+
+// token is a lexical token; pos is its byte offset in the template.
+type token struct {
+	kind tokenKind
+	text string      // Identifier, field or operator text
+	val  interface{} // Value of number and string literals
+	pos  int
+}
+
+// operators lists operators and punctuation, longest first.
+var operators = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", ",", "{", "}",
+}
+
+// lexer splits the expression part of a template into tokens. It works on the
+// whole template so the template scanner can hand over at any offset and
+// resume after the expression ends.
+type lexer struct {
+	src string
+	pos int
+}
+
+// next returns the next token, skipping whitespace.
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.IndexByte(" \t\r\n", l.src[l.pos]) >= 0 {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case isDigit(c) || c == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1]):
+		return l.number()
+	case c == '"' || c == '\'':
+		return l.string(c)
+	case c == '#' && l.pos+1 < len(l.src) && isWord(l.src[l.pos+1]):
+		l.pos = scanPath(l.src, l.pos+1)
+		return token{kind: tokField, text: l.src[start+1 : l.pos], pos: start}, nil
+	case c == '$' && l.pos+1 < len(l.src) && isIdentStart(l.src[l.pos+1]):
+		l.pos++
+		return token{kind: tokDollar, text: l.word(), pos: start}, nil
+	case isIdentStart(c):
+		return token{kind: tokIdent, text: l.word(), pos: start}, nil
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unexpected character '%c' at position %d", c, start)
+}
+
+// word reads a run of letters, digits and underscores.
+func (l *lexer) word() string {
+	start := l.pos
+	for l.pos < len(l.src) && isWord(l.src[l.pos]) {
+		l.pos++
+	}
+	return l.src[start:l.pos]
+}
+
+// scanPath returns the end of the field path starting at i: a word,
+// optionally followed by .word segments, e.g. parent.order_date.
+func scanPath(s string, i int) int {
+	for {
+		for i < len(s) && isWord(s[i]) {
+			i++
+		}
+		if i+1 >= len(s) || s[i] != '.' || !isWord(s[i+1]) {
+			return i
+		}
+		i++
+	}
+}
+
+// number reads an integer or decimal literal.
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	isFloat := false
+	for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+		if l.src[l.pos] == '.' {
+			if isFloat {
+				break
+			}
+			isFloat = true
+		}
+		l.pos++
+	}
+	text := l.src[start:l.pos]
+	if isFloat {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return token{}, fmt.Errorf("invalid number '%s' at position %d", text, start)
+		}
+		return token{kind: tokNumber, text: text, val: f, pos: start}, nil
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		// Too large for int64, e.g. the 9223372036854775808 of -9223372036854775808.
+		f, _ := strconv.ParseFloat(text, 64)
+		return token{kind: tokNumber, text: text, val: f, pos: start}, nil
+	}
+	return token{kind: tokNumber, text: text, val: n, pos: start}, nil
+}
+
+// string reads a literal in double or single quotes. \n, \t, \\ and an
+// escaped quote are unescaped; any other backslash is kept, so regular
+// expressions such as "\d{3}" need no double escaping.
+func (l *lexer) string(quote byte) (token, error) {
+	start := l.pos
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == quote:
+			l.pos++
+			return token{kind: tokString, text: l.src[start:l.pos], val: b.String(), pos: start}, nil
+		case c == '\\' && l.pos+1 < len(l.src):
+			l.pos++
+			switch e := l.src[l.pos]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '\\', '"', '\'':
+				b.WriteByte(e)
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+		l.pos++
+	}
+	return token{}, fmt.Errorf("unterminated string starting at position %d", start)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentStart(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isWord(c byte) bool { return isIdentStart(c) || isDigit(c) }
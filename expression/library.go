@@ -0,0 +1,323 @@
+package expression
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"likha/fake"
+	"likha/generator/types"
+	"likha/value"
+)
+
+// The functions below are deterministic transforms. String functions return
+// null for a null argument, so a missing optional field stays empty.
+
+// stringFunc adapts a string transform to an expression function.
+func stringFunc(f func(string) string) func(*types.Context, []interface{}) (interface{}, error) {
+	return func(ctx *types.Context, args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return f(format(args[0])), nil
+	}
+}
+
+var (
+	upper = stringFunc(strings.ToUpper)
+	lower = stringFunc(strings.ToLower)
+
+	// slug turns text into a lowercase, dash-separated URL slug: "Crème Brûlée!" becomes "creme-brulee".
+	slug = stringFunc(func(s string) string {
+		var b strings.Builder
+		dash := false
+		for _, c := range strings.ToLower(fake.FoldASCII(s)) {
+			if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+				if dash && b.Len() > 0 {
+					b.WriteByte('-')
+				}
+				b.WriteRune(c)
+				dash = false
+			} else {
+				dash = true
+			}
+		}
+		return b.String()
+	})
+
+	hashMD5 = stringFunc(func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	})
+	hashSHA256 = stringFunc(func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	})
+	base64Encode = stringFunc(func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	})
+)
+
+// substr implements substr(s, start[, length]). Positions count characters
+// from 0; a negative start counts from the end.
+func substr(ctx *types.Context, args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s := []rune(format(args[0]))
+	start, err := intArg(args, 1, "start", 0)
+	if err != nil {
+		return nil, err
+	}
+	n, err := intArg(args, 2, "length", int64(len(s)))
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 {
+		start = max(int64(len(s))+start, 0)
+	}
+	start = min(start, int64(len(s)))
+	end := min(start+max(n, 0), int64(len(s)))
+	return string(s[start:end]), nil
+}
+
+// pad implements pad(s, width[, char[, side]]), padding s to width characters
+// with char (default space) on the "left" (default) or "right".
+func pad(ctx *types.Context, args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s := format(args[0])
+	width, err := intArg(args, 1, "width", 0)
+	if err != nil {
+		return nil, err
+	}
+	char := " "
+	if v := arg(args, 2); v != nil {
+		char = format(v)
+		if utf8.RuneCountInString(char) != 1 {
+			return nil, fmt.Errorf("pad character must be a single character, got '%s'", char)
+		}
+	}
+	side := "left"
+	if v := arg(args, 3); v != nil {
+		side = format(v)
+	}
+	fill := strings.Repeat(char, max(int(width)-utf8.RuneCountInString(s), 0))
+	switch side {
+	case "left":
+		return fill + s, nil
+	case "right":
+		return s + fill, nil
+	}
+	return nil, fmt.Errorf("unknown pad side '%s' (expected left or right)", side)
+}
+
+// replace implements replace(s, old, new), replacing every occurrence.
+func replace(ctx *types.Context, args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return strings.ReplaceAll(format(args[0]), format(args[1]), format(args[2])), nil
+}
+
+// concat joins its arguments as text; null arguments are skipped.
+func concat(ctx *types.Context, args []interface{}) (interface{}, error) {
+	var b strings.Builder
+	for _, a := range args {
+		b.WriteString(format(a))
+	}
+	return b.String(), nil
+}
+
+// toTime reads a date as value.ToTime does. For strings it also returns the
+// layout that matched; numeric strings are Unix seconds.
+func toTime(v interface{}) (time.Time, string, error) {
+	if s, ok := v.(string); ok {
+		if _, isNum := toNumber(s); !isNum {
+			return value.ParseTime(s)
+		}
+	}
+	t, ok := value.ToTime(v)
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("cannot read %s as a date", describe(v))
+	}
+	return t, "", nil
+}
+
+// formatDate implements format_date(date, layout) with strftime-style
+// directives, e.g. format_date(#created, "%d/%m/%Y").
+func formatDate(ctx *types.Context, args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	t, _, err := toTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return strftime(t, format(args[1]))
+}
+
+// strftime formats t by a strftime-style layout.
+func strftime(t time.Time, layout string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			b.WriteByte(layout[i])
+			continue
+		}
+		if i++; i == len(layout) {
+			return "", fmt.Errorf("layout '%s' ends with '%%'", layout)
+		}
+		switch layout[i] {
+		case 'Y':
+			b.WriteString(t.Format("2006"))
+		case 'y':
+			b.WriteString(t.Format("06"))
+		case 'm':
+			b.WriteString(t.Format("01"))
+		case 'd':
+			b.WriteString(t.Format("02"))
+		case 'H':
+			b.WriteString(t.Format("15"))
+		case 'I':
+			b.WriteString(t.Format("03"))
+		case 'M':
+			b.WriteString(t.Format("04"))
+		case 'S':
+			b.WriteString(t.Format("05"))
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'b':
+			b.WriteString(t.Format("Jan"))
+		case 'B':
+			b.WriteString(t.Format("January"))
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'A':
+			b.WriteString(t.Format("Monday"))
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("unknown date directive '%%%c'", layout[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// dateAdd implements date_add(date, amount, unit), where unit is seconds,
+// minutes, hours, days, weeks, months or years. The result is in the same
+// form as the date: a timestamp, a date, Unix seconds or a string in the
+// same layout.
+func dateAdd(ctx *types.Context, args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	t, layout, err := toTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	n, err := intArg(args, 1, "amount", 0)
+	if err != nil {
+		return nil, err
+	}
+	switch unit := strings.TrimSuffix(format(args[2]), "s"); unit {
+	case "second":
+		t = t.Add(time.Duration(n) * time.Second)
+	case "minute":
+		t = t.Add(time.Duration(n) * time.Minute)
+	case "hour":
+		t = t.Add(time.Duration(n) * time.Hour)
+	case "day":
+		t = t.AddDate(0, 0, int(n))
+	case "week":
+		t = t.AddDate(0, 0, 7*int(n))
+	case "month":
+		t = addMonths(t, int(n))
+	case "year":
+		t = addMonths(t, 12*int(n))
+	default:
+		return nil, fmt.Errorf("unknown unit '%s' (expected seconds, minutes, hours, days, weeks, months or years)", format(args[2]))
+	}
+	switch args[0].(type) {
+	case time.Time:
+		return t, nil
+	case value.Date:
+		return value.NewDate(t), nil
+	}
+	switch layout {
+	case "":
+		return t.Unix(), nil
+	case time.RFC3339Nano:
+		return t.Format(time.RFC3339), nil
+	}
+	return t.Format(layout), nil
+}
+
+// addMonths adds months to t, keeping the day within the target month, so
+// January 31 plus one month is the last day of February.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// round implements round(x[, places]), rounding half away from zero. With
+// no places, or negative places such as -2 for hundreds, it returns an integer.
+func round(ctx *types.Context, args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	x, err := floatArg(args, 0, "value", 0)
+	if err != nil {
+		return nil, err
+	}
+	places, err := intArg(args, 1, "places", 0)
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Pow10(int(places))
+	v := math.Round(x*scale) / scale
+	if places <= 0 {
+		return int64(v), nil
+	}
+	return v, nil
+}
+
+// abs implements abs(x), keeping integers as integers.
+func abs(ctx *types.Context, args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	num, ok := toNumber(args[0])
+	if !ok {
+		return nil, fmt.Errorf("value must be a number, got %s", describe(args[0]))
+	}
+	if i, ok := num.(int64); ok {
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}
+	return math.Abs(num.(float64)), nil
+}
+
+// length implements len(s), the number of characters in s. Null has length 0.
+func length(ctx *types.Context, args []interface{}) (interface{}, error) {
+	return int64(utf8.RuneCountInString(format(args[0]))), nil
+}
@@ -0,0 +1,103 @@
+package expression
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"testing"
+	"time"
+
+	"likha/generator/types"
+	"likha/value"
+)
+
+func TestLibraryFunctions(t *testing.T) {
+	row := map[string]interface{}{
+		"name":    "Crème Brûlée!",
+		"missing": nil,
+		"jan31":   "2024-01-31",
+		"leap":    time.Date(2024, 2, 29, 10, 0, 0, 0, time.UTC),
+		"day":     value.NewDate(time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC)),
+		"ts":      time.Date(2024, 7, 4, 15, 5, 9, 0, time.UTC),
+	}
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{`$upper("abc")`, "ABC"},
+		{`$lower("AbC")`, "abc"},
+		{`$slug(#name)`, "creme-brulee"},
+		{`$slug("  Hello, World -- 2024 ")`, "hello-world-2024"},
+		{`$upper(#missing)`, nil},
+		{`$substr("abcdef", 2)`, "cdef"},
+		{`$substr("abcdef", 1, 3)`, "bcd"},
+		{`$substr("abcdef", -2)`, "ef"},
+		{`$substr("héllo", 1, 10)`, "éllo"},
+		{`$pad("7", 3, "0")`, "007"},
+		{`$pad("ab", 4, "*", "right")`, "ab**"},
+		{`$pad("abcdef", 3)`, "abcdef"},
+		{`$replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`$concat("a", #missing, 1, "b")`, "a1b"},
+		{`$format_date(#ts, "%Y-%m-%d %H:%M:%S")`, "2024-07-04 15:05:09"},
+		{`$format_date(#ts, "%a %d %b %y, %I:%M %p, day %j, %%")`, "Thu 04 Jul 24, 03:05 PM, day 186, %"},
+		{`$format_date(#ts, "%A %B %z %Z %s")`, "Thursday July +0000 UTC 1720105509"},
+		{`$format_date(0, "%Y")`, "1970"},
+		{`$date_add(#jan31, 1, "month")`, "2024-02-29"},
+		{`$date_add(#jan31, 3, "months")`, "2024-04-30"},
+		{`$date_add(#jan31, -2, "months")`, "2023-11-30"},
+		{`$date_add(#leap, 1, "year")`, time.Date(2025, 2, 28, 10, 0, 0, 0, time.UTC)},
+		{`$date_add(#day, 1, "month")`, value.NewDate(time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC))},
+		{`$date_add("2024-03-10T23:30:00Z", 45, "minutes")`, "2024-03-11T00:15:00Z"},
+		{`$date_add(86400, 1, "week")`, int64(8 * 86400)},
+		{`$hash_md5("abc")`, "900150983cd24fb0d6963f7d28e17f72"},
+		{`$sha256("abc")`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`$base64("hi")`, "aGk="},
+		{`$round(2.345, 2)`, 2.35},
+		{`$round(-2.5)`, int64(-3)},
+		{`$round(1234, -2)`, int64(1200)},
+		{`$abs(-5)`, int64(5)},
+		{`$abs(-2.5)`, 2.5},
+		{`$len("héllo")`, int64(5)},
+		{`$len(#missing)`, int64(0)},
+	}
+	ctx := &types.Context{Rand: rand.New(rand.NewPCG(1, 1))}
+	for _, tt := range tests {
+		tmpl, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		got, err := tmpl.Value(ctx, row)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestLibraryErrors(t *testing.T) {
+	row := map[string]interface{}{"ts": time.Date(2024, 7, 4, 0, 0, 0, 0, time.UTC)}
+	for _, expr := range []string{
+		`$pad("a", 3, "ab")`,
+		`$pad("a", 3, "0", "middle")`,
+		`$format_date(#ts, "%Q")`,
+		`$format_date(#ts, "%")`,
+		`$format_date("soon", "%Y")`,
+		`$date_add(#ts, 1, "fortnight")`,
+		`$abs("x")`,
+	} {
+		tmpl, err := Compile(expr)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		if got, err := tmpl.Value(&types.Context{Rand: rand.New(rand.NewPCG(1, 1))}, row); err == nil {
+			t.Errorf("%s = %#v, expected an error", expr, got)
+		}
+	}
+	if _, err := Compile(`$upper("a", "b")`); err == nil {
+		t.Error("a wrong number of arguments should be rejected when compiling")
+	}
+}
@@ -0,0 +1,280 @@
+package expression
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parse compiles a template into its parts: literal text, field references,
+// function calls and ${...} expressions.
+func parse(template string) ([]node, error) {
+	var parts []node
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, &literal{val: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); {
+		c := template[i]
+		var next byte
+		if i+1 < len(template) {
+			next = template[i+1]
+		}
+		switch {
+		case (c == '#' || c == '$') && next == c:
+			// ## and $$ escape a literal # or $.
+			text.WriteByte(c)
+			i += 2
+
+		case c == '#' && isWord(next):
+			j := scanPath(template, i+1)
+			flush()
+			parts = append(parts, newFieldRef(template[i+1:j], false))
+			i = j
+
+		case c == '$' && next == '{':
+			p, err := newParser(template, i+2)
+			if err != nil {
+				return nil, err
+			}
+			expr, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if !p.is("}") {
+				return nil, p.errorf("expected '}'")
+			}
+			flush()
+			parts = append(parts, expr)
+			i = p.lex.pos
+
+		case c == '$' && isIdentStart(next):
+			j := i + 1
+			for j < len(template) && isWord(template[j]) {
+				j++
+			}
+			if j >= len(template) || template[j] != '(' {
+				// Not a call, e.g. a price like "$USD"; keep it as text.
+				text.WriteString(template[i:j])
+				i = j
+				continue
+			}
+			p, err := newParser(template, i)
+			if err != nil {
+				return nil, err
+			}
+			call, err := p.call()
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			parts = append(parts, call)
+			i = p.lex.pos // Just past the closing parenthesis
+
+		default:
+			text.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return parts, nil
+}
+
+// parser is a recursive descent parser for expressions, with one token of lookahead.
+type parser struct {
+	lex lexer
+	tok token
+}
+
+func newParser(src string, pos int) (*parser, error) {
+	p := &parser{lex: lexer{src: src, pos: pos}}
+	return p, p.advance()
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+// is reports whether the current token is the given operator.
+func (p *parser) is(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	found := p.tok.text
+	if p.tok.kind == tokEOF {
+		found = "end of expression"
+	}
+	return fmt.Errorf("%s at position %d, found '%s'", fmt.Sprintf(format, args...), p.tok.pos, found)
+}
+
+// expression parses a full expression. The conditional operator binds
+// loosest and is right-associative: a ? b : c ? d : e is a ? b : (c ? d : e).
+func (p *parser) expression() (node, error) {
+	cond, err := p.binary(0)
+	if err != nil || !p.is("?") {
+		return cond, err
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	then, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.is(":") {
+		return nil, p.errorf("expected ':'")
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &conditional{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// precedence lists binary operators from lowest to highest precedence.
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// binary parses left-associative binary operators at the given precedence level and above.
+func (p *parser) binary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && contains(precedence[level], p.tok.text) {
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binary{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.is("-") || p.is("!") {
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{op: op, x: x}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber, tokString:
+		return &literal{val: tok.val}, p.advance()
+
+	case tokField:
+		return newFieldRef(tok.text, true), p.advance()
+
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return &literal{val: tok.text == "true"}, p.advance()
+		case "null":
+			return &literal{val: nil}, p.advance()
+		}
+		fallthrough
+	case tokDollar:
+		n, err := p.call()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.advance() // Past the closing parenthesis
+
+	case tokOp:
+		if tok.text == "(" {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			n, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if !p.is(")") {
+				return nil, p.errorf("expected ')'")
+			}
+			return n, p.advance()
+		}
+	}
+	return nil, p.errorf("expected a value")
+}
+
+// call parses name(args), with or without a leading $. It stops on the
+// closing parenthesis without reading past it, because in a template the
+// text that follows is not part of the expression.
+func (p *parser) call() (node, error) {
+	name := p.tok.text
+	if p.tok.kind != tokIdent && p.tok.kind != tokDollar {
+		return nil, p.errorf("expected a function name")
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if !p.is("(") {
+		return nil, p.errorf("unknown name '%s'; field references start with # and function calls need parentheses", name)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var args []node
+	for !p.is(")") {
+		if len(args) > 0 {
+			if !p.is(",") {
+				return nil, p.errorf("expected ',' or ')' in arguments of %s", name)
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return newCall(name, args)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
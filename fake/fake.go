@@ -0,0 +1,249 @@
+package fake
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is used when no locale is configured.
+const DefaultLocale = "en_US"
+
+//go:embed data/*.json
+var dataFS embed.FS
+
+// Name is a personal name in the locale's script, with a Latin form for emails and usernames.
+type Name struct {
+	Native string
+	Latin  string
+}
+
+// UnmarshalJSON reads a name written as "Native" or "Native|Latin".
+// Without an explicit Latin form, it is derived by folding diacritics.
+func (n *Name) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	native, latin, ok := strings.Cut(s, "|")
+	if !ok {
+		latin = FoldASCII(native)
+	}
+	*n = Name{Native: native, Latin: latin}
+	return nil
+}
+
+// City is a city together with the attributes that must agree with it in an address.
+type City struct {
+	Name      string  `json:"name"`
+	State     string  `json:"state"`
+	StateCode string  `json:"state_code"`
+	Postal    string  `json:"postal"` // Postal code pattern, see Fill
+	Lat       float64 `json:"lat"`
+	Lon       float64 `json:"lon"`
+}
+
+// Locale is the embedded dataset for one locale.
+type Locale struct {
+	Code           string
+	Country        string   `json:"country"`
+	CountryCode    string   `json:"country_code"`
+	NameFormat     string   `json:"name_format"` // e.g. "{first} {last}"
+	MaleNames      []Name   `json:"male_names"`
+	FemaleNames    []Name   `json:"female_names"`
+	LastNames      []Name   `json:"last_names"`
+	StreetFormats  []string `json:"street_formats"` // e.g. "%## {street}"
+	Streets        []string `json:"streets"`
+	Cities         []City   `json:"cities"`
+	PhoneFormats   []string `json:"phone_formats"`
+	EmailDomains   []string `json:"email_domains"`
+	CompanyFormats []string `json:"company_formats"` // e.g. "{last} GmbH"
+	JobTitles      []string `json:"job_titles"`
+}
+
+var (
+	loadOnce sync.Once
+	locales  map[string]*Locale
+	loadErr  error
+)
+
+// Get returns the dataset for a locale code such as "de_DE".
+func Get(code string) (*Locale, error) {
+	loadOnce.Do(load)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	if code == "" {
+		code = DefaultLocale
+	}
+	l, ok := locales[code]
+	if !ok {
+		return nil, fmt.Errorf("unknown locale '%s' (available: %s)", code, strings.Join(Codes(), ", "))
+	}
+	return l, nil
+}
+
+// Codes lists the available locale codes.
+func Codes() []string {
+	loadOnce.Do(load)
+	codes := make([]string, 0, len(locales))
+	for code := range locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+func load() {
+	entries, err := dataFS.ReadDir("data")
+	if err != nil {
+		loadErr = err
+		return
+	}
+	locales = make(map[string]*Locale, len(entries))
+	for _, e := range entries {
+		data, err := dataFS.ReadFile("data/" + e.Name())
+		if err != nil {
+			loadErr = err
+			return
+		}
+		var l Locale
+		if err := json.Unmarshal(data, &l); err != nil {
+			loadErr = fmt.Errorf("invalid locale data %s: %w", e.Name(), err)
+			return
+		}
+		l.Code = strings.TrimSuffix(e.Name(), ".json")
+		locales[l.Code] = &l
+	}
+}
+
+// FirstName picks a first name. gender is "male", "female" or "" for either.
+func (l *Locale) FirstName(r *rand.Rand, gender string) Name {
+	switch gender {
+	case "male":
+		return pick(r, l.MaleNames)
+	case "female":
+		return pick(r, l.FemaleNames)
+	}
+	if r.IntN(2) == 0 {
+		return pick(r, l.MaleNames)
+	}
+	return pick(r, l.FemaleNames)
+}
+
+// LastName picks a last name.
+func (l *Locale) LastName(r *rand.Rand) Name {
+	return pick(r, l.LastNames)
+}
+
+// FullName formats a first and last name in the locale's order.
+func (l *Locale) FullName(first, last Name) string {
+	return strings.NewReplacer("{first}", first.Native, "{last}", last.Native).Replace(l.NameFormat)
+}
+
+// StreetAddress generates a street line, e.g. "742 Maple Drive" or "Hauptstraße 12".
+func (l *Locale) StreetAddress(r *rand.Rand) string {
+	format := pick(r, l.StreetFormats)
+	return Fill(r, strings.ReplaceAll(format, "{street}", pick(r, l.Streets)))
+}
+
+// City picks a city.
+func (l *Locale) City(r *rand.Rand) *City {
+	return &l.Cities[r.IntN(len(l.Cities))]
+}
+
+// PostalCode generates a postal code valid for the given city.
+func (l *Locale) PostalCode(r *rand.Rand, c *City) string {
+	return Fill(r, c.Postal)
+}
+
+// Phone generates a phone number in one of the locale's formats.
+func (l *Locale) Phone(r *rand.Rand) string {
+	return Fill(r, pick(r, l.PhoneFormats))
+}
+
+// emailFormats are the local-part patterns for email addresses, using Latin names.
+var emailFormats = []string{
+	"{first}.{last}",
+	"{first}{last}##",
+	"{f}{last}",
+	"{first}_{last}",
+	"{last}.{first}%#",
+	"{first}%##",
+}
+
+// Email generates an email address matching the given name.
+func (l *Locale) Email(r *rand.Rand, first, last Name) string {
+	return Username(r, first, last, pick(r, emailFormats)) + "@" + pick(r, l.EmailDomains)
+}
+
+// Username builds a lowercase handle from a name using a pattern such as "{first}.{last}".
+func Username(r *rand.Rand, first, last Name, format string) string {
+	f := strings.ToLower(first.Latin)
+	s := strings.NewReplacer(
+		"{first}", strings.ReplaceAll(f, " ", ""),
+		"{last}", strings.ReplaceAll(strings.ToLower(last.Latin), " ", ""),
+		"{f}", f[:1],
+	).Replace(format)
+	return Fill(r, s)
+}
+
+// Company generates a company name.
+func (l *Locale) Company(r *rand.Rand) string {
+	format := pick(r, l.CompanyFormats)
+	// Each {last} gets its own name, e.g. "Smith & Jones".
+	var b strings.Builder
+	for {
+		before, after, ok := strings.Cut(format, "{last}")
+		b.WriteString(before)
+		if !ok {
+			break
+		}
+		b.WriteString(l.LastName(r).Native)
+		format = after
+	}
+	return b.String()
+}
+
+// JobTitle picks a job title.
+func (l *Locale) JobTitle(r *rand.Rand) string {
+	return pick(r, l.JobTitles)
+}
+
+// Fill replaces each '#' in pattern with a random digit and each '%' with a non-zero digit.
+func Fill(r *rand.Rand, pattern string) string {
+	if !strings.ContainsAny(pattern, "#%") {
+		return pattern
+	}
+	b := []byte(pattern)
+	for i, c := range b {
+		switch c {
+		case '#':
+			b[i] = byte('0' + r.IntN(10))
+		case '%':
+			b[i] = byte('1' + r.IntN(9))
+		}
+	}
+	return string(b)
+}
+
+func pick[T any](r *rand.Rand, list []T) T {
+	return list[r.IntN(len(list))]
+}
+
+// asciiFolds maps accented Latin letters to plain ASCII.
+var asciiFolds = strings.NewReplacer(
+	"ä", "ae", "ö", "oe", "ü", "ue", "Ä", "Ae", "Ö", "Oe", "Ü", "Ue", "ß", "ss",
+	"à", "a", "á", "a", "â", "a", "ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "ù", "u", "û", "u", "œ", "oe", "ÿ", "y",
+	"À", "A", "Â", "A", "Ç", "C", "È", "E", "É", "E", "Ê", "E", "Î", "I", "Ô", "O", "Œ", "Oe",
+)
+
+// FoldASCII replaces accented Latin letters with plain ASCII, e.g. "Müller" with "Mueller".
+func FoldASCII(s string) string {
+	return asciiFolds.Replace(s)
+}
@@ -2,19 +2,24 @@ package builtin
 
 import (
 	"fmt"
-	"math/rand"
+	"math"
 	"time"
 
+	"likha/distribution"
 	"likha/generator/types"
 
 	"likha/util"
+	"likha/value"
 )
 
 // BuiltinGenerator uses predefined functions to generate data.
 type BuiltinGenerator struct {
-	function func() (interface{}, error)
+	function builtinFunc
 }
 
+// builtinFunc generates one value from the record's context and the row generated so far.
+type builtinFunc func(ctx *types.Context, row map[string]interface{}) (interface{}, error)
+
 // New creates a new BuiltinGenerator.
 func New(settings map[string]interface{}) (types.Generator, error) {
 	funcName, ok := settings["function"].(string)
@@ -22,34 +27,47 @@ func New(settings map[string]interface{}) (types.Generator, error) {
 		return nil, fmt.Errorf("builtin generator requires a 'function' string setting")
 	}
 
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	var f func() (interface{}, error)
+	var f builtinFunc
+	var err error
 	switch funcName {
 	case "random_epoch":
-		f = makeRandomEpoch(r, settings)
+		f = makeRandomEpoch(settings)
 	case "random_isodate":
-		f = makeRandomISODate(r, settings)
+		f = makeRandomISODate(settings)
 	case "random_string":
-		f = makeRandomString(r, settings)
+		f = makeRandomString(settings)
 	case "random_int":
-		f = makeRandomInt(r, settings)
+		f, err = makeRandomInt(settings)
 	case "random_decimal":
-		f = makeRandomDecimal(r, settings)
+		f, err = makeRandomDecimal(settings)
+	case "uuid_v4":
+		f = makeUUIDv4(settings)
+	case "uuid_v7":
+		f, err = makeUUIDv7(settings)
+	case "ulid":
+		f, err = makeULID(settings)
+	case "uuid_v5":
+		f, err = makeUUIDv5(settings)
+	case "first_name", "last_name", "full_name", "street_address", "city", "state", "postal_code",
+		"country", "phone", "email", "company", "job_title":
+		f, err = makeFake(funcName, settings)
 	default:
 		return nil, fmt.Errorf("unknown builtin function: %s", funcName)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("builtin function %s: %w", funcName, err)
+	}
 
 	return &BuiltinGenerator{function: f}, nil
 }
 
 // Generate calls the configured builtin function.
-func (g *BuiltinGenerator) Generate(row map[string]interface{}) (interface{}, error) {
-	return g.function()
+func (g *BuiltinGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	return g.function(ctx, row)
 }
 
 // Helper functions to create the specific generator functions
-func makeRandomEpoch(r *rand.Rand, s map[string]interface{}) func() (interface{}, error) {
+func makeRandomEpoch(s map[string]interface{}) builtinFunc {
 	start := time.Now().Add(-365 * 24 * time.Hour).Unix()
 	end := time.Now().Unix()
 	if v, ok := s["start"]; ok {
@@ -58,12 +76,14 @@ func makeRandomEpoch(r *rand.Rand, s map[string]interface{}) func() (interface{}
 	if v, ok := s["end"]; ok {
 		end, _ = util.InterfaceToInt64(v)
 	}
-	return func() (interface{}, error) {
-		return r.Int63n(end-start+1) + start, nil
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		return ctx.Rand.Int64N(end-start+1) + start, nil
 	}
 }
 
-func makeRandomISODate(r *rand.Rand, s map[string]interface{}) func() (interface{}, error) {
+// dateRange reads the optional start_date/end_date (RFC 3339) settings,
+// defaulting to the year before now.
+func dateRange(s map[string]interface{}) (time.Time, time.Time) {
 	start := time.Now().Add(-365 * 24 * time.Hour)
 	end := time.Now()
 	if v, ok := s["start_date"]; ok {
@@ -78,14 +98,19 @@ func makeRandomISODate(r *rand.Rand, s map[string]interface{}) func() (interface
 			end = parsed
 		}
 	}
+	return start, end
+}
+
+func makeRandomISODate(s map[string]interface{}) builtinFunc {
+	start, end := dateRange(s)
 	diff := end.Unix() - start.Unix()
-	return func() (interface{}, error) {
-		sec := r.Int63n(diff) + start.Unix()
-		return time.Unix(sec, 0).Format(time.RFC3339), nil
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		sec := ctx.Rand.Int64N(diff) + start.Unix()
+		return time.Unix(sec, 0), nil
 	}
 }
 
-func makeRandomString(r *rand.Rand, s map[string]interface{}) func() (interface{}, error) {
+func makeRandomString(s map[string]interface{}) builtinFunc {
 	length := 10
 	charset := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	if v, ok := s["length"]; ok {
@@ -95,16 +120,16 @@ func makeRandomString(r *rand.Rand, s map[string]interface{}) func() (interface{
 	if v, ok := s["charset"]; ok {
 		charset = v.(string)
 	}
-	return func() (interface{}, error) {
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
 		b := make([]byte, length)
 		for i := range b {
-			b[i] = charset[r.Intn(len(charset))]
+			b[i] = charset[ctx.Rand.IntN(len(charset))]
 		}
 		return string(b), nil
 	}
 }
 
-func makeRandomInt(r *rand.Rand, s map[string]interface{}) func() (interface{}, error) {
+func makeRandomInt(s map[string]interface{}) (builtinFunc, error) {
 	min := 0
 	max := 100
 	if v, ok := s["min"]; ok {
@@ -113,12 +138,21 @@ func makeRandomInt(r *rand.Rand, s map[string]interface{}) func() (interface{},
 	if v, ok := s["max"]; ok {
 		max, _ = util.InterfaceToInt(v)
 	}
-	return func() (interface{}, error) {
-		return r.Intn(max-min+1) + min, nil
+	sampler, err := distribution.FromSettings(s, float64(min), float64(max))
+	if err != nil {
+		return nil, err
+	}
+	if sampler != nil {
+		return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+			return int(math.Round(sampler.Sample(ctx.Rand))), nil
+		}, nil
 	}
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		return ctx.Rand.IntN(max-min+1) + min, nil
+	}, nil
 }
 
-func makeRandomDecimal(r *rand.Rand, s map[string]interface{}) func() (interface{}, error) {
+func makeRandomDecimal(s map[string]interface{}) (builtinFunc, error) {
 	min := 0.0
 	max := 100.0
 	places := 2
@@ -131,8 +165,17 @@ func makeRandomDecimal(r *rand.Rand, s map[string]interface{}) func() (interface
 	if v, ok := s["places"]; ok {
 		places, _ = util.InterfaceToInt(v)
 	}
-	return func() (interface{}, error) {
-		val := min + r.Float64()*(max-min)
-		return fmt.Sprintf(fmt.Sprintf("%%.%df", places), val), nil
+	sampler, err := distribution.FromSettings(s, min, max)
+	if err != nil {
+		return nil, err
 	}
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		var val float64
+		if sampler != nil {
+			val = sampler.Sample(ctx.Rand)
+		} else {
+			val = min + ctx.Rand.Float64()*(max-min)
+		}
+		return value.NewDecimal(val, places), nil
+	}, nil
 }
@@ -0,0 +1,85 @@
+package builtin
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"likha/generator/types"
+)
+
+// sample generates n values of a builtin function, one per record index.
+func sample(t *testing.T, settings map[string]interface{}, n int) []interface{} {
+	t.Helper()
+	g, err := New(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := types.NewContext()
+	out := make([]interface{}, n)
+	for i := range out {
+		ctx.Reset(int64(i), 42)
+		if out[i], err = g.Generate(ctx, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return out
+}
+
+func TestLocaleFormats(t *testing.T) {
+	tests := []struct {
+		locale        string
+		phone, postal string
+	}{
+		{"en_US", `^(\(\d{3}\) \d{3}-\d{4}|\d{3}-\d{3}-\d{4}|\+1 \d{3}-\d{3}-\d{4})$`, `^\d{5}$`},
+		{"de_DE", `^(\+49 30 [1-9]\d{6}|\+49 15\d [1-9]\d{7}|0[1-9]\d\d [1-9]\d{6})$`, `^\d{5}$`},
+		{"fr_FR", `^(\+33 6|0[167])( \d\d){4}$`, `^\d{5}$`},
+		{"hi_IN", `^(\+91 [789]\d{9}|0[1-9]\d-[1-9]\d{7})$`, `^\d{6}$`},
+		{"ja_JP", `^(0[789]0|03)-\d{4}-\d{4}$`, `^\d{3}-\d{4}$`},
+	}
+	for _, tt := range tests {
+		for _, check := range []struct{ function, pattern string }{{"phone", tt.phone}, {"postal_code", tt.postal}} {
+			re := regexp.MustCompile(check.pattern)
+			for _, v := range sample(t, map[string]interface{}{"function": check.function, "locale": tt.locale}, 200) {
+				if !re.MatchString(v.(string)) {
+					t.Fatalf("%s %s %q does not match %s", tt.locale, check.function, v, check.pattern)
+				}
+			}
+		}
+	}
+}
+
+// TestTimeOrderedIDs checks that uuid_v7 and ulid values increase with the
+// record index, and that an index past end_date fails.
+func TestTimeOrderedIDs(t *testing.T) {
+	for _, function := range []string{"uuid_v7", "ulid"} {
+		settings := map[string]interface{}{"function": function, "start_date": "2024-01-01T00:00:00Z", "end_date": "2024-01-01T00:00:01Z"}
+		ids := make([]string, 0, 1000)
+		for _, v := range sample(t, settings, 1000) {
+			ids = append(ids, v.(string))
+		}
+		if !slices.IsSorted(ids) || len(slices.Compact(slices.Clone(ids))) != len(ids) {
+			t.Errorf("%s values do not increase with the index", function)
+		}
+
+		settings["step"] = "10ms"
+		g, err := New(settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx := types.NewContext()
+		ctx.Reset(100, 42)
+		if _, err := g.Generate(ctx, nil); err != nil {
+			t.Errorf("%s record 100: %v", function, err)
+		}
+		ctx.Reset(101, 42)
+		if _, err := g.Generate(ctx, nil); err == nil {
+			t.Errorf("%s record 101 is after end_date and should fail", function)
+		}
+
+		settings["step"] = "1us"
+		if _, err := New(settings); err == nil {
+			t.Errorf("%s with a step under 1ms should fail", function)
+		}
+	}
+}
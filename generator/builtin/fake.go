@@ -0,0 +1,54 @@
+package builtin
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"likha/fake"
+	"likha/generator/types"
+)
+
+// makeFake builds the fake-data functions, which read from the embedded
+// dataset of the 'locale' setting (default en_US).
+func makeFake(funcName string, s map[string]interface{}) (builtinFunc, error) {
+	code, _ := s["locale"].(string)
+	l, err := fake.Get(code)
+	if err != nil {
+		return nil, err
+	}
+	gender, _ := s["gender"].(string)
+	if gender != "" && gender != "male" && gender != "female" {
+		return nil, fmt.Errorf("unknown gender '%s' (expected male or female)", gender)
+	}
+
+	var gen func(r *rand.Rand) string
+	switch funcName {
+	case "first_name":
+		gen = func(r *rand.Rand) string { return l.FirstName(r, gender).Native }
+	case "last_name":
+		gen = func(r *rand.Rand) string { return l.LastName(r).Native }
+	case "full_name":
+		gen = func(r *rand.Rand) string { return l.FullName(l.FirstName(r, gender), l.LastName(r)) }
+	case "street_address":
+		gen = l.StreetAddress
+	case "city":
+		gen = func(r *rand.Rand) string { return l.City(r).Name }
+	case "state":
+		gen = func(r *rand.Rand) string { return l.City(r).State }
+	case "postal_code":
+		gen = func(r *rand.Rand) string { return l.PostalCode(r, l.City(r)) }
+	case "country":
+		gen = func(r *rand.Rand) string { return l.Country }
+	case "phone":
+		gen = l.Phone
+	case "email":
+		gen = func(r *rand.Rand) string { return l.Email(r, l.FirstName(r, gender), l.LastName(r)) }
+	case "company":
+		gen = l.Company
+	case "job_title":
+		gen = l.JobTitle
+	}
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		return gen(ctx.Rand), nil
+	}, nil
+}
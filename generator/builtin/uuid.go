@@ -0,0 +1,100 @@
+package builtin
+
+import (
+	"fmt"
+	"time"
+
+	"likha/expression"
+	"likha/generator/types"
+	"likha/util"
+	"likha/uuid"
+)
+
+func makeUUIDv4(s map[string]interface{}) builtinFunc {
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		return uuid.NewV4(ctx.Rand).String(), nil
+	}
+}
+
+func makeUUIDv7(s map[string]interface{}) (builtinFunc, error) {
+	stamp, err := indexTime(s)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		t, err := stamp(ctx.Index)
+		if err != nil {
+			return nil, err
+		}
+		return uuid.NewV7(ctx.Rand, t).String(), nil
+	}, nil
+}
+
+func makeULID(s map[string]interface{}) (builtinFunc, error) {
+	stamp, err := indexTime(s)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		t, err := stamp(ctx.Index)
+		if err != nil {
+			return nil, err
+		}
+		return uuid.NewULID(ctx.Rand, t), nil
+	}, nil
+}
+
+// indexTime reads the start_date, end_date and step settings of a
+// time-ordered ID. Record i is stamped start_date + i*step (step defaults to
+// 1ms), so the IDs follow the record order and only their random bits vary.
+func indexTime(s map[string]interface{}) (func(index int64) (time.Time, error), error) {
+	start, end := dateRange(s)
+	step := time.Millisecond
+	if v, ok := s["step"]; ok {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("'step' must be a duration string")
+		}
+		var err error
+		if step, err = util.ParseDuration(str); err != nil {
+			return nil, fmt.Errorf("invalid 'step': %w", err)
+		}
+	}
+	if _, err := uuid.IndexMillis(0, start, end, step); err != nil {
+		return nil, err
+	}
+	return func(index int64) (time.Time, error) {
+		return uuid.IndexMillis(index, start, end, step)
+	}, nil
+}
+
+// makeUUIDv5 builds name-based UUIDs. The 'name' setting is an expression
+// template, so it can combine other fields, e.g. "user:#email".
+func makeUUIDv5(s map[string]interface{}) (builtinFunc, error) {
+	nsName := "url"
+	if v, ok := s["namespace"]; ok {
+		nsName, ok = v.(string)
+		if !ok {
+			return nil, fmt.Errorf("'namespace' must be a string")
+		}
+	}
+	ns, err := uuid.ParseNamespace(nsName)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := s["name"].(string)
+	if !ok {
+		return nil, fmt.Errorf("requires a 'name' string setting")
+	}
+	template, err := expression.Compile(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'name' expression: %w", err)
+	}
+	return func(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+		resolved, err := template.Evaluate(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		return uuid.NewV5(ns, resolved).String(), nil
+	}, nil
+}
@@ -33,7 +33,7 @@ func New(settings map[string]interface{}) (types.Generator, error) {
 }
 
 // Generate executes the external command and returns its standard output.
-func (g *CustomGenerator) Generate(row map[string]interface{}) (interface{}, error) {
+func (g *CustomGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
 	cmd := exec.Command(g.command, g.args...)
 	output, err := cmd.Output()
 	if err != nil {
@@ -0,0 +1,215 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"likha/util"
+)
+
+// correlated draws its variables together from a multivariate normal
+// distribution with the given correlation matrix, then maps each onto its
+// own distribution (a Gaussian copula). Fields project the variables with
+// 'from', so e.g. age and income keep their correlation across fields.
+type correlated struct {
+	vars []variable
+	chol [][]float64 // Lower-triangular Cholesky factor of the correlation matrix
+}
+
+// variable is one correlated value and its marginal distribution.
+type variable struct {
+	name         string
+	dist         string // normal, lognormal or uniform
+	mean, stddev float64
+	min, max     float64 // Bounds; ±Inf when unset
+}
+
+// newCorrelated reads the 'variables' and 'correlation' settings.
+func newCorrelated(settings map[string]interface{}) (*correlated, error) {
+	list, ok := settings["variables"].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("a correlated entity needs 'variables', a list of variables with a name")
+	}
+	c := &correlated{}
+	for i, item := range list {
+		s, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("variable %d must be a mapping", i+1)
+		}
+		v, err := newVariable(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range c.vars {
+			if other.name == v.name {
+				return nil, fmt.Errorf("variable '%s' is defined twice", v.name)
+			}
+		}
+		c.vars = append(c.vars, v)
+	}
+
+	corr, err := matrix(settings["correlation"], len(c.vars))
+	if err != nil {
+		return nil, err
+	}
+	c.chol, err = cholesky(corr)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newVariable reads one variable: name, distribution, mean, stddev, min and max.
+func newVariable(s map[string]interface{}) (variable, error) {
+	v := variable{dist: "normal", stddev: 1, min: math.Inf(-1), max: math.Inf(1)}
+	v.name, _ = s["name"].(string)
+	if v.name == "" {
+		return v, fmt.Errorf("every variable needs a name")
+	}
+	if d, ok := s["distribution"]; ok {
+		v.dist, _ = d.(string)
+	}
+	numbers := []struct {
+		name string
+		dst  *float64
+	}{{"mean", &v.mean}, {"stddev", &v.stddev}, {"min", &v.min}, {"max", &v.max}}
+	for _, n := range numbers {
+		if x, ok := s[n.name]; ok {
+			f, ok := util.InterfaceToFloat64(x)
+			if !ok {
+				return v, fmt.Errorf("variable '%s': '%s' must be a number", v.name, n.name)
+			}
+			*n.dst = f
+		}
+	}
+	if v.min > v.max {
+		return v, fmt.Errorf("variable '%s': min cannot be greater than max", v.name)
+	}
+	switch v.dist {
+	case "normal":
+		if v.stddev < 0 {
+			return v, fmt.Errorf("variable '%s': stddev must not be negative", v.name)
+		}
+	case "lognormal":
+		if v.mean <= 0 || v.stddev <= 0 {
+			return v, fmt.Errorf("variable '%s': lognormal mean and stddev must be positive", v.name)
+		}
+		// Convert the mean and stddev of the values to those of the underlying normal.
+		sigma2 := math.Log(1 + (v.stddev*v.stddev)/(v.mean*v.mean))
+		v.mean, v.stddev = math.Log(v.mean)-sigma2/2, math.Sqrt(sigma2)
+	case "uniform":
+		if math.IsInf(v.min, 0) || math.IsInf(v.max, 0) {
+			return v, fmt.Errorf("variable '%s': a uniform variable needs min and max", v.name)
+		}
+	default:
+		return v, fmt.Errorf("variable '%s': unknown distribution '%s' (expected normal, lognormal or uniform)", v.name, v.dist)
+	}
+	return v, nil
+}
+
+// matrix reads an n×n correlation matrix: symmetric, with ones on the
+// diagonal and entries between -1 and 1. Without one, the variables are
+// independent.
+func matrix(setting interface{}, n int) ([][]float64, error) {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	if setting == nil {
+		return m, nil
+	}
+	rows, ok := setting.([]interface{})
+	if !ok || len(rows) != n {
+		return nil, fmt.Errorf("'correlation' must be a %d×%d matrix, one row per variable", n, n)
+	}
+	for i, row := range rows {
+		cols, ok := row.([]interface{})
+		if !ok || len(cols) != n {
+			return nil, fmt.Errorf("'correlation' must be a %d×%d matrix, one row per variable", n, n)
+		}
+		for j, x := range cols {
+			f, ok := util.InterfaceToFloat64(x)
+			if !ok || f < -1 || f > 1 {
+				return nil, fmt.Errorf("'correlation' entries must be numbers between -1 and 1")
+			}
+			m[i][j] = f
+		}
+	}
+	for i := range m {
+		if m[i][i] != 1 {
+			return nil, fmt.Errorf("'correlation' must have 1 on its diagonal")
+		}
+		for j := range i {
+			if m[i][j] != m[j][i] {
+				return nil, fmt.Errorf("'correlation' must be symmetric")
+			}
+		}
+	}
+	return m, nil
+}
+
+// cholesky returns the lower-triangular L with L·Lᵀ = m.
+func cholesky(m [][]float64) ([][]float64, error) {
+	n := len(m)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, i+1)
+		for j := 0; j <= i; j++ {
+			sum := m[i][j]
+			for k := range j {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, fmt.Errorf("'correlation' is not a valid correlation matrix: some of its correlations contradict each other")
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
+
+// names returns the variable names, which are the entity's attributes.
+func (c *correlated) names() []string {
+	names := make([]string, len(c.vars))
+	for i, v := range c.vars {
+		names[i] = v.name
+	}
+	return names
+}
+
+// sample draws one value of every variable.
+func (c *correlated) sample(r *rand.Rand) map[string]interface{} {
+	z := make([]float64, len(c.vars))
+	for i := range z {
+		z[i] = r.NormFloat64()
+	}
+	out := make(map[string]interface{}, len(c.vars))
+	for i, v := range c.vars {
+		var y float64
+		for k, l := range c.chol[i] {
+			y += l * z[k]
+		}
+		out[v.name] = v.value(y)
+	}
+	return out
+}
+
+// value maps a standard normal draw onto the variable's distribution.
+func (v variable) value(y float64) float64 {
+	var x float64
+	switch v.dist {
+	case "lognormal":
+		x = math.Exp(v.mean + v.stddev*y)
+	case "uniform":
+		x = v.min + (v.max-v.min)*0.5*math.Erfc(-y/math.Sqrt2)
+	default:
+		x = v.mean + v.stddev*y
+	}
+	return math.Min(math.Max(x, v.min), v.max)
+}
@@ -0,0 +1,147 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"likha/fake"
+	"likha/generator/types"
+)
+
+// Attributes lists the attributes of each entity type.
+var Attributes = map[string][]string{
+	"person": {"gender", "first_name", "last_name", "full_name", "email", "username", "phone"},
+	"address": {"street_address", "city", "state", "state_code", "postal_code", "country", "country_code",
+		"latitude", "longitude"},
+}
+
+// usernameFormats are the patterns for person usernames.
+var usernameFormats = []string{"{first}.{last}", "{f}{last}##", "{first}_{last}%#", "{first}{last}"}
+
+// coordinateJitter is how far, in degrees, a generated point may lie from the city center.
+const coordinateJitter = 0.05
+
+// EntityGenerator generates a composite value whose attributes are consistent
+// with each other, e.g. a city together with its own state and postal code.
+// Each record gets one entity as a map from attribute name to value.
+type EntityGenerator struct {
+	kind   string
+	attrs  []string
+	locale *fake.Locale
+	gender string
+	corr   *correlated // For the correlated type
+}
+
+// New creates an EntityGenerator of the given type ("person", "address" or
+// "correlated"). Person and address accept a 'locale' setting; person also
+// accepts a fixed 'gender'. A correlated entity is defined by its settings.
+func New(kind string, settings map[string]interface{}) (*EntityGenerator, error) {
+	if kind == "correlated" {
+		c, err := newCorrelated(settings)
+		if err != nil {
+			return nil, err
+		}
+		return &EntityGenerator{kind: kind, attrs: c.names(), corr: c}, nil
+	}
+	if _, ok := Attributes[kind]; !ok {
+		return nil, fmt.Errorf("unknown entity type '%s' (expected person, address or correlated)", kind)
+	}
+	code, _ := settings["locale"].(string)
+	l, err := fake.Get(code)
+	if err != nil {
+		return nil, err
+	}
+	gender, _ := settings["gender"].(string)
+	if gender != "" && gender != "male" && gender != "female" {
+		return nil, fmt.Errorf("unknown gender '%s' (expected male or female)", gender)
+	}
+	return &EntityGenerator{kind: kind, attrs: Attributes[kind], locale: l, gender: gender}, nil
+}
+
+// Has reports whether the entity has the given attribute.
+func (g *EntityGenerator) Has(attr string) bool {
+	for _, a := range g.attrs {
+		if a == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate returns the entity's attributes for this record.
+func (g *EntityGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	switch g.kind {
+	case "person":
+		return g.person(ctx.Rand), nil
+	case "correlated":
+		return g.corr.sample(ctx.Rand), nil
+	}
+	return g.address(ctx.Rand), nil
+}
+
+func (g *EntityGenerator) person(r *rand.Rand) map[string]interface{} {
+	l := g.locale
+	gender := g.gender
+	if gender == "" {
+		gender = "male"
+		if r.IntN(2) == 1 {
+			gender = "female"
+		}
+	}
+	first := l.FirstName(r, gender)
+	last := l.LastName(r)
+	return map[string]interface{}{
+		"gender":     gender,
+		"first_name": first.Native,
+		"last_name":  last.Native,
+		"full_name":  l.FullName(first, last),
+		"email":      l.Email(r, first, last),
+		"username":   fake.Username(r, first, last, usernameFormats[r.IntN(len(usernameFormats))]),
+		"phone":      l.Phone(r),
+	}
+}
+
+func (g *EntityGenerator) address(r *rand.Rand) map[string]interface{} {
+	l := g.locale
+	city := l.City(r)
+	return map[string]interface{}{
+		"street_address": l.StreetAddress(r),
+		"city":           city.Name,
+		"state":          city.State,
+		"state_code":     city.StateCode,
+		"postal_code":    l.PostalCode(r, city),
+		"country":        l.Country,
+		"country_code":   l.CountryCode,
+		"latitude":       jitter(r, city.Lat),
+		"longitude":      jitter(r, city.Lon),
+	}
+}
+
+// jitter moves a coordinate by up to coordinateJitter degrees, rounded to 6 places (about 10 cm).
+func jitter(r *rand.Rand, deg float64) float64 {
+	v := deg + (r.Float64()*2-1)*coordinateJitter
+	return math.Round(v*1e6) / 1e6
+}
+
+// Projection is a field generator that reads one attribute of an entity
+// generated earlier in the same record.
+type Projection struct {
+	entity string
+	key    string
+	attr   string
+}
+
+// NewProjection creates a Projection of attr from the named entity.
+func NewProjection(name, attr string) *Projection {
+	return &Projection{entity: name, key: types.RowKey(name), attr: attr}
+}
+
+// Generate returns the attribute's value.
+func (p *Projection) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	e, ok := row[p.key].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("entity '%s' not generated", p.entity)
+	}
+	return e[p.attr], nil
+}
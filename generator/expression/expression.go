@@ -8,25 +8,27 @@ import (
 	"likha/generator/types"
 )
 
-// ExpressionGenerator evaluates an expression string.
+// ExpressionGenerator evaluates an expression template.
 type ExpressionGenerator struct {
-	evaluator *expression.Evaluator
-	template  string
+	template *expression.Template
 }
 
-// New creates a new ExpressionGenerator.
+// New creates a new ExpressionGenerator. The template is compiled here, so
+// syntax errors are reported when the config is loaded.
 func New(settings map[string]interface{}) (types.Generator, error) {
-	template, ok := settings["expression"].(string)
+	source, ok := settings["expression"].(string)
 	if !ok {
 		return nil, fmt.Errorf("expression generator requires an 'expression' string setting")
 	}
-	return &ExpressionGenerator{
-		evaluator: expression.NewEvaluator(),
-		template:  template,
-	}, nil
+	template, err := expression.Compile(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+	return &ExpressionGenerator{template: template}, nil
 }
 
-// Generate evaluates the expression.
-func (g *ExpressionGenerator) Generate(row map[string]interface{}) (interface{}, error) {
-	return g.evaluator.Evaluate(g.template, row)
+// Generate evaluates the expression. The result keeps its type when the
+// expression is a single call, reference or ${...} expression.
+func (g *ExpressionGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	return g.template.Value(ctx, row)
 }
@@ -8,8 +8,14 @@ import (
 	"likha/generator/custom"
 	"likha/generator/expression"
 	"likha/generator/foreignkey"
+	"likha/generator/linear"
 	"likha/generator/list"
+	"likha/generator/regex"
+	"likha/generator/sequence"
+	"likha/generator/series"
 	"likha/generator/simple"
+	"likha/generator/text"
+	"likha/generator/timeseries"
 	"likha/generator/types"
 )
 
@@ -27,6 +33,18 @@ func NewGenerator(cfg config.GeneratorConfig, allGenerators map[string]types.Gen
 		return expression.New(cfg.Settings)
 	case "custom":
 		return custom.New(cfg.Settings)
+	case "sequence":
+		return sequence.New(cfg.Settings)
+	case "regex":
+		return regex.New(cfg.Settings)
+	case "timeseries":
+		return timeseries.New(cfg.Settings)
+	case "series":
+		return series.New(cfg.Settings)
+	case "linear":
+		return linear.New(cfg.Settings)
+	case "text":
+		return text.New(cfg.Settings)
 	case "foreignkey":
 		return foreignkey.New(cfg, allGenerators, NewGenerator)
 	default:
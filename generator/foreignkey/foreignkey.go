@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"likha/config"
+	"likha/distribution"
 
 	"likha/generator/types"
 )
@@ -32,8 +33,8 @@ func New(
 		valueMap:    make(map[interface{}]types.Generator),
 	}
 
-	for key, genCfg := range cfg.Map {
-		gen, err := factoryFn(genCfg, allGenerators)
+	for key, choices := range cfg.Map {
+		gen, err := newChoice(choices, allGenerators, factoryFn)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create generator for foreign key map value '%v': %w", key, err)
 		}
@@ -43,8 +44,53 @@ func New(
 	return g, nil
 }
 
+// weightedChoice picks one of several generators per record, by weight.
+type weightedChoice struct {
+	generators []types.Generator
+	alias      *distribution.Alias
+}
+
+// newChoice creates the generator for one map entry. A single generator is
+// used as is; several are wrapped in a weightedChoice. A missing weight is 1.
+func newChoice(
+	choices config.GeneratorChoices,
+	allGenerators map[string]types.Generator,
+	factoryFn func(config.GeneratorConfig, map[string]types.Generator) (types.Generator, error),
+) (types.Generator, error) {
+	if len(choices) == 0 {
+		return nil, fmt.Errorf("no generator given")
+	}
+	gens := make([]types.Generator, len(choices))
+	weights := make([]float64, len(choices))
+	for i, c := range choices {
+		gen, err := factoryFn(c, allGenerators)
+		if err != nil {
+			return nil, err
+		}
+		gens[i] = gen
+		weights[i] = 1
+		if c.Weight != nil {
+			weights[i] = *c.Weight
+		}
+	}
+	// A weight of 0 excludes its generator; negative weights are rejected here.
+	alias, err := distribution.NewAlias(weights)
+	if err != nil {
+		return nil, err
+	}
+	if len(gens) == 1 {
+		return gens[0], nil
+	}
+	return &weightedChoice{generators: gens, alias: alias}, nil
+}
+
+// Generate delegates to a generator picked by weight.
+func (c *weightedChoice) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	return c.generators[c.alias.Sample(ctx.Rand)].Generate(ctx, row)
+}
+
 // Generate looks up the source field's value and uses the corresponding generator.
-func (g *ForeignKeyGenerator) Generate(row map[string]interface{}) (interface{}, error) {
+func (g *ForeignKeyGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
 	sourceValue, ok := row[g.sourceField]
 	if !ok {
 		// This can happen if the source field hasn't been generated yet for this row.
@@ -58,5 +104,5 @@ func (g *ForeignKeyGenerator) Generate(row map[string]interface{}) (interface{},
 		return nil, nil
 	}
 
-	return mappedGenerator.Generate(row)
+	return mappedGenerator.Generate(ctx, row)
 }
@@ -0,0 +1,131 @@
+package linear
+
+import (
+	"fmt"
+	"math"
+
+	"likha/generator/types"
+	"likha/util"
+	"likha/value"
+)
+
+// LinearGenerator computes a number from earlier fields of the record:
+//
+//	intercept + coefficient₁·field₁ + coefficient₂·field₂ + ... + noise
+//
+// With additive noise, a normal error with the given stddev is added. With
+// multiplicative noise, the result is scaled by a lognormal factor instead,
+// so the spread grows with the value, as incomes do.
+type LinearGenerator struct {
+	fields       []string
+	coefficients []float64
+	intercept    float64
+	noise        float64
+	relative     bool // Multiplicative noise
+	min, max     float64
+}
+
+// New creates a LinearGenerator.
+func New(settings map[string]interface{}) (types.Generator, error) {
+	g := &LinearGenerator{min: math.Inf(-1), max: math.Inf(1)}
+	switch v := settings["depends_on"].(type) {
+	case string:
+		g.fields = []string{v}
+	case []interface{}:
+		for _, f := range v {
+			name, ok := f.(string)
+			if !ok {
+				return nil, fmt.Errorf("linear 'depends_on' must be a field name or a list of field names")
+			}
+			g.fields = append(g.fields, name)
+		}
+	}
+	if len(g.fields) == 0 {
+		return nil, fmt.Errorf("linear requires 'depends_on', a field name or a list of field names")
+	}
+
+	g.coefficients = make([]float64, len(g.fields))
+	switch v := settings["coefficients"].(type) {
+	case nil:
+		for i := range g.coefficients {
+			g.coefficients[i] = 1
+		}
+	case []interface{}:
+		if len(v) != len(g.fields) {
+			return nil, fmt.Errorf("linear 'coefficients' must have one number per 'depends_on' field")
+		}
+		for i, c := range v {
+			f, ok := util.InterfaceToFloat64(c)
+			if !ok {
+				return nil, fmt.Errorf("linear 'coefficients' must be numbers")
+			}
+			g.coefficients[i] = f
+		}
+	default:
+		f, ok := util.InterfaceToFloat64(v)
+		if !ok || len(g.fields) != 1 {
+			return nil, fmt.Errorf("linear 'coefficients' must have one number per 'depends_on' field")
+		}
+		g.coefficients[0] = f
+	}
+
+	numbers := []struct {
+		name string
+		dst  *float64
+	}{{"intercept", &g.intercept}, {"noise", &g.noise}, {"min", &g.min}, {"max", &g.max}}
+	for _, n := range numbers {
+		if v, ok := settings[n.name]; ok {
+			f, ok := util.InterfaceToFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("linear '%s' must be a number", n.name)
+			}
+			*n.dst = f
+		}
+	}
+	if g.noise < 0 {
+		return nil, fmt.Errorf("linear 'noise' must not be negative")
+	}
+	if g.min > g.max {
+		return nil, fmt.Errorf("linear 'min' cannot be greater than 'max'")
+	}
+	switch model, _ := settings["noise_model"].(string); model {
+	case "", "additive":
+	case "multiplicative":
+		g.relative = true
+	default:
+		return nil, fmt.Errorf("linear 'noise_model' must be additive or multiplicative, got '%s'", model)
+	}
+	return g, nil
+}
+
+// DependsOn returns the fields the value is computed from.
+func (g *LinearGenerator) DependsOn() []string {
+	return g.fields
+}
+
+// Generate returns the computed value, or null if any input is null.
+func (g *LinearGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	y := g.intercept
+	for i, name := range g.fields {
+		v, ok := row[name]
+		if !ok {
+			return nil, fmt.Errorf("linear 'depends_on' field '%s' not found; it must come before this field", name)
+		}
+		x, err := value.Coerce(v, "float", -1)
+		if err != nil {
+			return nil, fmt.Errorf("linear 'depends_on' field '%s': %w", name, err)
+		}
+		if x == nil {
+			return nil, nil
+		}
+		y += g.coefficients[i] * x.(float64)
+	}
+	if g.noise > 0 {
+		if g.relative {
+			y *= math.Exp(g.noise*ctx.Rand.NormFloat64() - g.noise*g.noise/2)
+		} else {
+			y += g.noise * ctx.Rand.NormFloat64()
+		}
+	}
+	return math.Min(math.Max(y, g.min), g.max), nil
+}
@@ -2,19 +2,20 @@ package list
 
 import (
 	"fmt"
-	"math/rand"
-	"time"
 
+	"likha/distribution"
 	"likha/generator/types"
+	"likha/util"
 )
 
-// ListGenerator randomly selects a value from a list.
+// ListGenerator randomly selects a value from a list, uniformly or by weight.
 type ListGenerator struct {
 	values []interface{}
-	r      *rand.Rand
+	alias  *distribution.Alias // Nil for uniform selection
 }
 
-// New creates a new ListGenerator.
+// New creates a new ListGenerator. Weights are given either as a 'weights'
+// list parallel to 'values', or inline as values of the form {value: x, weight: n}.
 func New(settings map[string]interface{}) (types.Generator, error) {
 	v, ok := settings["values"]
 	if !ok {
@@ -26,16 +27,81 @@ func New(settings map[string]interface{}) (types.Generator, error) {
 		return nil, fmt.Errorf("'values' setting must be a list")
 	}
 
-	return &ListGenerator{
-		values: values,
-		r:      rand.New(rand.NewSource(time.Now().UnixNano())),
-	}, nil
+	values, weights, err := parseWeights(values, settings["weights"])
+	if err != nil {
+		return nil, err
+	}
+
+	g := &ListGenerator{values: values}
+	if weights != nil && len(values) > 0 {
+		g.alias, err = distribution.NewAlias(weights)
+		if err != nil {
+			return nil, fmt.Errorf("invalid list weights: %w", err)
+		}
+	}
+	return g, nil
+}
+
+// parseWeights extracts the plain values and their weights, if any were given.
+func parseWeights(values []interface{}, rawWeights interface{}) ([]interface{}, []float64, error) {
+	if rawWeights != nil {
+		list, ok := rawWeights.([]interface{})
+		if !ok {
+			return nil, nil, fmt.Errorf("'weights' setting must be a list")
+		}
+		if len(list) != len(values) {
+			return nil, nil, fmt.Errorf("'weights' has %d entries but 'values' has %d", len(list), len(values))
+		}
+		weights := make([]float64, len(list))
+		for i, w := range list {
+			f, ok := util.InterfaceToFloat64(w)
+			if !ok {
+				return nil, nil, fmt.Errorf("weight %d must be a number", i)
+			}
+			weights[i] = f
+		}
+		return values, weights, nil
+	}
+
+	// Inline form: every entry is {value: x, weight: n}.
+	if len(values) == 0 || !isWeightedEntry(values[0]) {
+		return values, nil, nil
+	}
+	plain := make([]interface{}, len(values))
+	weights := make([]float64, len(values))
+	for i, entry := range values {
+		if !isWeightedEntry(entry) {
+			return nil, nil, fmt.Errorf("value %d: when weights are given inline, every value must have the form {value: x, weight: n}", i)
+		}
+		m := entry.(map[string]interface{})
+		f, ok := util.InterfaceToFloat64(m["weight"])
+		if !ok {
+			return nil, nil, fmt.Errorf("value %d: 'weight' must be a number", i)
+		}
+		plain[i] = m["value"]
+		weights[i] = f
+	}
+	return plain, weights, nil
+}
+
+// isWeightedEntry reports whether v is a {value: x, weight: n} mapping.
+func isWeightedEntry(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok || len(m) != 2 {
+		return false
+	}
+	_, hasValue := m["value"]
+	_, hasWeight := m["weight"]
+	return hasValue && hasWeight
 }
 
 // Generate returns a random value from the list.
-func (g *ListGenerator) Generate(row map[string]interface{}) (interface{}, error) {
+func (g *ListGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
 	if len(g.values) == 0 {
 		return nil, nil
 	}
-	return g.values[g.r.Intn(len(g.values))], nil
+	if g.alias != nil {
+		return g.values[g.alias.Sample(ctx.Rand)], nil
+	}
+	return g.values[ctx.Rand.IntN(len(g.values))], nil
 }
@@ -0,0 +1,40 @@
+package regex
+
+import (
+	"fmt"
+
+	"likha/generator/types"
+	"likha/regexgen"
+	"likha/util"
+)
+
+// RegexGenerator produces random strings matching a regular expression.
+type RegexGenerator struct {
+	gen *regexgen.Generator
+}
+
+// New creates a new RegexGenerator from the 'pattern' setting. 'max_repeat'
+// (default 10) caps how many extra times *, + and {n,} repeat.
+func New(settings map[string]interface{}) (types.Generator, error) {
+	pattern, ok := settings["pattern"].(string)
+	if !ok {
+		return nil, fmt.Errorf("regex generator requires a 'pattern' string setting")
+	}
+	maxRepeat := regexgen.DefaultMaxRepeat
+	if v, ok := settings["max_repeat"]; ok {
+		maxRepeat, ok = util.InterfaceToInt(v)
+		if !ok {
+			return nil, fmt.Errorf("'max_repeat' must be an integer")
+		}
+	}
+	gen, err := regexgen.Compile(pattern, maxRepeat)
+	if err != nil {
+		return nil, fmt.Errorf("regex generator: %w", err)
+	}
+	return &RegexGenerator{gen: gen}, nil
+}
+
+// Generate returns a string matching the pattern.
+func (g *RegexGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	return g.gen.Generate(ctx.Rand), nil
+}
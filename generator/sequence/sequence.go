@@ -0,0 +1,80 @@
+package sequence
+
+import (
+	"fmt"
+
+	"likha/generator/types"
+	"likha/util"
+)
+
+// SequenceGenerator produces auto-incrementing values derived from the record index,
+// so they stay gap-free and in order no matter which worker generates a record.
+type SequenceGenerator struct {
+	start  int64
+	step   int64
+	period uint64 // Number of values before wrapping around; 0 means never wrap
+	format string
+}
+
+// New creates a new SequenceGenerator.
+func New(settings map[string]interface{}) (types.Generator, error) {
+	g := &SequenceGenerator{start: 1, step: 1}
+	if v, ok := settings["start"]; ok {
+		start, ok := util.InterfaceToInt64(v)
+		if !ok {
+			return nil, fmt.Errorf("sequence 'start' must be an integer")
+		}
+		g.start = start
+	}
+	if v, ok := settings["step"]; ok {
+		step, ok := util.InterfaceToInt64(v)
+		if !ok || step == 0 {
+			return nil, fmt.Errorf("sequence 'step' must be a non-zero integer")
+		}
+		g.step = step
+	}
+	if v, ok := settings["end"]; ok {
+		end, ok := util.InterfaceToInt64(v)
+		if !ok {
+			return nil, fmt.Errorf("sequence 'end' must be an integer")
+		}
+		// The sequence wraps back to start after the last value that does not pass end.
+		if (g.step > 0 && end < g.start) || (g.step < 0 && end > g.start) {
+			return nil, fmt.Errorf("sequence 'end' (%d) is not reachable from 'start' (%d) with 'step' (%d)", end, g.start, g.step)
+		}
+		// Unsigned arithmetic keeps ranges wider than int64 exact. A range of
+		// all 2^64 values overflows to a period of 0, and never needs to wrap.
+		span, step := uint64(end)-uint64(g.start), uint64(g.step)
+		if g.step < 0 {
+			span, step = uint64(g.start)-uint64(end), -uint64(g.step)
+		}
+		g.period = span/step + 1
+	}
+	if v, ok := settings["format"]; ok {
+		format, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("sequence 'format' must be a string")
+		}
+		g.format = format
+	}
+	return g, nil
+}
+
+// Generate returns the sequence value for the current record index.
+func (g *SequenceGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	val := g.start + ctx.Index*g.step
+	if g.period > 0 {
+		// Take the index modulo the period, non-negative even for a negative index.
+		var n uint64
+		if ctx.Index >= 0 {
+			n = uint64(ctx.Index) % g.period
+		} else {
+			n = g.period - 1 - uint64(-(ctx.Index+1))%g.period
+		}
+		val = int64(uint64(g.start) + n*uint64(g.step))
+	}
+	if g.format != "" {
+		return fmt.Sprintf(g.format, val), nil
+	}
+	return val, nil
+}
@@ -0,0 +1,60 @@
+package sequence
+
+import (
+	"math"
+	"testing"
+
+	"likha/generator/types"
+)
+
+func TestSequence(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		indexes  []int64
+		want     []interface{}
+	}{
+		{"defaults", nil, []int64{0, 1, 2}, []interface{}{int64(1), int64(2), int64(3)}},
+		{"start and step", map[string]interface{}{"start": 100, "step": 5}, []int64{0, 1, 10}, []interface{}{int64(100), int64(105), int64(150)}},
+		{"negative start", map[string]interface{}{"start": -3}, []int64{0, 3, 5}, []interface{}{int64(-3), int64(0), int64(2)}},
+		{"format", map[string]interface{}{"start": 7, "format": "ORD-%05d"}, []int64{0, 1}, []interface{}{"ORD-00007", "ORD-00008"}},
+		{"wrap at end", map[string]interface{}{"start": 1, "end": 3}, []int64{0, 2, 3, 4, 7}, []interface{}{int64(1), int64(3), int64(1), int64(2), int64(2)}},
+		{"end between steps", map[string]interface{}{"start": 0, "step": 4, "end": 10}, []int64{2, 3}, []interface{}{int64(8), int64(0)}},
+		{"negative step", map[string]interface{}{"start": -2, "step": -3, "end": -10}, []int64{0, 2, 3, 4}, []interface{}{int64(-2), int64(-8), int64(-2), int64(-5)}},
+		{"negative index", map[string]interface{}{"start": 1, "end": 3}, []int64{-1, -3, -4, math.MinInt64}, []interface{}{int64(3), int64(1), int64(3), int64(2)}},
+		{"negative index and step", map[string]interface{}{"start": 10, "step": -5, "end": 0}, []int64{-1, -2}, []interface{}{int64(0), int64(5)}},
+		{"range wider than int64", map[string]interface{}{"start": math.MinInt64 + 1, "step": 2, "end": math.MaxInt64}, []int64{0, math.MaxInt64, -1},
+			[]interface{}{int64(math.MinInt64 + 1), int64(math.MaxInt64), int64(math.MaxInt64)}},
+	}
+	ctx := types.NewContext()
+	for _, tt := range tests {
+		g, err := New(tt.settings)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for i, index := range tt.indexes {
+			ctx.Reset(index, 0)
+			got, err := g.Generate(ctx, nil)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if got != tt.want[i] {
+				t.Errorf("%s: record %d is %v, want %v", tt.name, index, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestSequenceSettings(t *testing.T) {
+	for _, settings := range []map[string]interface{}{
+		{"step": 0},
+		{"start": "one"},
+		{"start": 5, "end": 1},
+		{"start": 1, "step": -1, "end": 5},
+		{"format": 3},
+	} {
+		if _, err := New(settings); err == nil {
+			t.Errorf("New(%v) should fail", settings)
+		}
+	}
+}
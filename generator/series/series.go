@@ -0,0 +1,196 @@
+package series
+
+import (
+	"fmt"
+	"math"
+
+	"likha/generator/types"
+	"likha/util"
+	"likha/value"
+)
+
+// SeriesGenerator returns, for record i,
+//
+//	base + drift·i + step·walk(i) + amplitude·cos(2π·(position-peak)/period) + noise
+//
+// where walk is a random walk with standard normal steps and position is the
+// record index or, with a time field, that field's time in seconds. The walk
+// is built top-down as a Brownian bridge, so each value is computed from its
+// index alone in walkDepth steps, without the records before it.
+type SeriesGenerator struct {
+	base, drift, step, noise float64
+	amplitude, period, peak  float64 // period and peak in records, or seconds with timeField
+	timeField                string
+	min, max                 *float64
+}
+
+// walkDepth bounds the walk to the first 2^walkDepth records.
+const walkDepth = 48
+
+// walkSalt separates the walk from other uses of the field seed.
+const walkSalt = 0x77616c6b00000000
+
+// New creates a SeriesGenerator.
+func New(settings map[string]interface{}) (types.Generator, error) {
+	g := &SeriesGenerator{}
+	numbers := []struct {
+		name string
+		dst  *float64
+	}{{"base", &g.base}, {"drift", &g.drift}, {"step", &g.step}, {"noise", &g.noise}, {"amplitude", &g.amplitude}}
+	for _, n := range numbers {
+		if v, ok := settings[n.name]; ok {
+			f, ok := util.InterfaceToFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("series '%s' must be a number", n.name)
+			}
+			*n.dst = f
+		}
+	}
+	if g.step < 0 || g.noise < 0 {
+		return nil, fmt.Errorf("series 'step' and 'noise' must not be negative")
+	}
+	if g.drift == 0 && g.step == 0 && g.noise == 0 && g.amplitude == 0 {
+		return nil, fmt.Errorf("series needs at least one of 'step', 'amplitude', 'drift' or 'noise'")
+	}
+
+	if v, ok := settings["time_field"]; ok {
+		g.timeField, _ = v.(string)
+		if g.timeField == "" {
+			return nil, fmt.Errorf("series 'time_field' must be a field name")
+		}
+	}
+	var err error
+	if g.amplitude != 0 {
+		if g.period, err = g.position(settings["period"]); err != nil || g.period <= 0 {
+			return nil, fmt.Errorf("series 'period' must be a positive %s", g.unit())
+		}
+	}
+	if v, ok := settings["peak"]; ok {
+		if g.peak, err = g.position(v); err != nil {
+			return nil, fmt.Errorf("series 'peak' must be a %s", g.unit())
+		}
+	}
+
+	for _, name := range []string{"min", "max"} {
+		if v, ok := settings[name]; ok {
+			f, ok := util.InterfaceToFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("series '%s' must be a number", name)
+			}
+			if name == "min" {
+				g.min = &f
+			} else {
+				g.max = &f
+			}
+		}
+	}
+	if g.min != nil && g.max != nil && *g.min > *g.max {
+		return nil, fmt.Errorf("series 'min' cannot be greater than 'max'")
+	}
+	return g, nil
+}
+
+// position reads a period or peak: a number of records, or a duration such
+// as "24h" with a time field.
+func (g *SeriesGenerator) position(v interface{}) (float64, error) {
+	if g.timeField == "" {
+		f, ok := util.InterfaceToFloat64(v)
+		if !ok {
+			return 0, fmt.Errorf("not a number")
+		}
+		return f, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("not a duration")
+	}
+	d, err := util.ParseDuration(s)
+	return d.Seconds(), err
+}
+
+func (g *SeriesGenerator) unit() string {
+	if g.timeField == "" {
+		return "number of records"
+	}
+	return "duration such as 24h, as 'time_field' is set"
+}
+
+// Generate returns the series value of the current record.
+func (g *SeriesGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	i := ctx.Index
+	v := g.base + g.drift*float64(i)
+	if g.step > 0 {
+		if i < 0 || i >= 1<<walkDepth {
+			return nil, fmt.Errorf("series walk is limited to the first 2^%d records", walkDepth)
+		}
+		v += g.step * walk(ctx.Seed, i)
+	}
+	if g.amplitude != 0 {
+		pos := float64(i)
+		if g.timeField != "" {
+			t, ok := value.ToTime(row[g.timeField])
+			if !ok {
+				return nil, fmt.Errorf("series 'time_field' '%s' holds no timestamp", g.timeField)
+			}
+			pos = float64(t.UnixNano()) / 1e9
+		}
+		v += g.amplitude * math.Cos(2*math.Pi*(pos-g.peak)/g.period)
+	}
+	if g.noise > 0 {
+		v += g.noise * ctx.Rand.NormFloat64()
+	}
+	return g.bound(v), nil
+}
+
+// bound keeps v within min and max: reflected back from the bounds when
+// both are set, so a walk bounces off them, and clamped to a single bound.
+func (g *SeriesGenerator) bound(v float64) float64 {
+	switch {
+	case g.min != nil && g.max != nil:
+		lo, width := *g.min, *g.max-*g.min
+		if width == 0 {
+			return lo
+		}
+		m := math.Mod(v-lo, 2*width)
+		if m < 0 {
+			m += 2 * width
+		}
+		if m > width {
+			m = 2*width - m
+		}
+		return lo + m
+	case g.min != nil:
+		return math.Max(v, *g.min)
+	case g.max != nil:
+		return math.Min(v, *g.max)
+	}
+	return v
+}
+
+// walk returns the position at step i of a random walk that starts at 0.
+// The walk's end point at 2^walkDepth is drawn first; each level of the
+// bridge then draws the midpoint of the interval that holds i given its two
+// ends, which is exactly how the walk is distributed there.
+func walk(seed int64, i int64) float64 {
+	a, b := int64(0), int64(1)<<walkDepth
+	wa, wb := 0.0, math.Sqrt(float64(b))*gaussian(seed, b)
+	for b-a > 1 {
+		m := (a + b) / 2
+		wm := (wa+wb)/2 + math.Sqrt(float64(b-a)/4)*gaussian(seed, m)
+		if i < m {
+			b, wb = m, wm
+		} else {
+			a, wa = m, wm
+		}
+	}
+	return wa
+}
+
+// gaussian returns a standard normal number for the bridge point at index
+// m. Every point is the midpoint of exactly one interval, so m identifies it.
+func gaussian(seed int64, m int64) float64 {
+	x := util.Mix64(uint64(seed) ^ util.Mix64(uint64(m)) ^ walkSalt)
+	u1 := (float64(x>>11) + 1) / (1 << 53) // (0, 1], so the logarithm is finite
+	u2 := float64(util.Mix64(x)>>11) / (1 << 53)
+	return math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+}
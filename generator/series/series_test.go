@@ -0,0 +1,126 @@
+package series
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"likha/generator/types"
+	"likha/value"
+)
+
+func newSeries(t *testing.T, settings map[string]interface{}) types.Generator {
+	t.Helper()
+	g, err := New(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestSeriesIsDeterministic(t *testing.T) {
+	g := newSeries(t, map[string]interface{}{"base": 10, "step": 0.5, "drift": 0.01, "amplitude": 2, "period": 50, "noise": 0.1})
+	const n = 1000
+	forward := make([]interface{}, n)
+	ctx := types.NewContext()
+	for i := range forward {
+		ctx.Reset(int64(i), 7)
+		v, err := g.Generate(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forward[i] = v
+	}
+	// Each value depends on its index and seed alone, not on the records before it.
+	for i := n - 1; i >= 0; i -= 3 {
+		ctx := types.NewContext()
+		ctx.Reset(int64(i), 7)
+		v, err := g.Generate(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != forward[i] {
+			t.Fatalf("record %d is %v alone, %v in order", i, v, forward[i])
+		}
+	}
+	ctx.Reset(5, 8)
+	if v, _ := g.Generate(ctx, nil); v == forward[5] {
+		t.Fatalf("another seed gave the same value %v", v)
+	}
+}
+
+func TestSeriesBounds(t *testing.T) {
+	tests := []struct {
+		min, max interface{}
+	}{
+		{-1, 1},
+		{0, nil},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		settings := map[string]interface{}{"step": 1, "noise": 2}
+		if tt.min != nil {
+			settings["min"] = tt.min
+		}
+		if tt.max != nil {
+			settings["max"] = tt.max
+		}
+		g := newSeries(t, settings)
+		ctx := types.NewContext()
+		for i := int64(0); i < 5000; i++ {
+			ctx.Reset(i, 3)
+			v, err := g.Generate(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := v.(float64)
+			if tt.min != nil && f < float64(tt.min.(int)) || tt.max != nil && f > float64(tt.max.(int)) {
+				t.Fatalf("min %v, max %v: record %d is %v", tt.min, tt.max, i, f)
+			}
+		}
+	}
+}
+
+func TestSeriesTimeField(t *testing.T) {
+	g := newSeries(t, map[string]interface{}{"base": 20, "amplitude": 5, "period": "24h", "peak": "15h", "time_field": "ts"})
+	day := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ts   interface{}
+		want float64
+	}{
+		{day.Add(15 * time.Hour), 25},
+		{day.Add(3 * time.Hour), 15},
+		{"2024-06-01T15:00:00Z", 25},
+		{value.NewDate(day), 20 + 5*math.Cos(2*math.Pi*-15/24)},
+		{day.Add(27 * time.Hour).Unix(), 15},
+	}
+	ctx := types.NewContext()
+	for _, tt := range tests {
+		v, err := g.Generate(ctx, map[string]interface{}{"ts": tt.ts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(v.(float64)-tt.want) > 1e-9 {
+			t.Errorf("at %v: got %v, want %v", tt.ts, v, tt.want)
+		}
+	}
+	if _, err := g.Generate(ctx, map[string]interface{}{"ts": "noon"}); err == nil {
+		t.Error("a time field that is not a time should fail")
+	}
+}
+
+func TestSeriesSettings(t *testing.T) {
+	for _, settings := range []map[string]interface{}{
+		{},
+		{"step": -1},
+		{"base": "high", "step": 1},
+		{"amplitude": 1},
+		{"amplitude": 1, "period": "24h"},
+		{"amplitude": 1, "period": 10, "time_field": "ts"},
+		{"step": 1, "min": 5, "max": 1},
+	} {
+		if _, err := New(settings); err == nil {
+			t.Errorf("New(%v) should fail", settings)
+		}
+	}
+}
@@ -21,6 +21,6 @@ func New(settings map[string]interface{}) (types.Generator, error) {
 }
 
 // Generate returns the static value.
-func (g *SimpleGenerator) Generate(row map[string]interface{}) (interface{}, error) {
+func (g *SimpleGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
 	return g.value, nil
 }
@@ -0,0 +1,38 @@
+package text
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// loremWords is the vocabulary of lorem ipsum text.
+var loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation
+ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate velit
+esse cillum eu fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt culpa qui
+officia deserunt mollit anim id est laborum curabitur pretium tincidunt lacus nunc pulvinar sapien
+ligula mauris vitae ultricies leo integer malesuada vestibulum morbi blandit cursus risus at
+ultrices mi tempus imperdiet nulla facilisi etiam dignissim diam quisque sagittis purus semper
+eget duis pellentesque habitant tristique senectus netus fames turpis egestas maecenas pharetra
+convallis posuere orci varius natoque penatibus magnis dis parturient montes nascetur ridiculus mus
+viverra accumsan felis fermentum iaculis eros donec ac odio tellus faucibus scelerisque eleifend
+vulputate sollicitudin aliquam nibh massa placerat vel hendrerit gravida arcu quam lectus`)
+
+// lorem writes sentences of random lorem ipsum words.
+type lorem struct{}
+
+// sentence returns min to max words, capitalized and ending in a period,
+// with the occasional comma.
+func (lorem) sentence(r *rand.Rand, min, max int) []string {
+	n := min + r.IntN(max-min+1)
+	words := make([]string, n)
+	for i := range words {
+		words[i] = loremWords[r.IntN(len(loremWords))]
+		if i > 1 && i < n-2 && r.IntN(8) == 0 {
+			words[i-1] += ","
+		}
+	}
+	words[0] = capitalize(words[0])
+	words[n-1] += "."
+	return words
+}
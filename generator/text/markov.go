@@ -0,0 +1,81 @@
+package text
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markov writes sentences from a word-level Markov chain trained on a
+// corpus: each word is drawn from the words that follow the previous order
+// words somewhere in the corpus, as often as they do there. The chain is
+// built once, when the config is loaded, and only read afterwards.
+type markov struct {
+	order  int
+	starts [][]string          // The first order words of every sentence of the corpus
+	next   map[string][]string // Followers of each run of order words, with repeats
+}
+
+// newMarkov trains a chain of the given order on the text of a file.
+func newMarkov(path string, order int) (*markov, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read corpus: %w", err)
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("corpus '%s' is not UTF-8 text", path)
+	}
+	words := strings.Fields(string(data))
+	m := &markov{order: order, next: make(map[string][]string)}
+	for i := 0; i+order < len(words); i++ {
+		state := words[i : i+order]
+		if i == 0 || endsSentence(words[i-1]) {
+			m.starts = append(m.starts, state)
+		}
+		k := key(state)
+		m.next[k] = append(m.next[k], words[i+order])
+	}
+	if len(m.starts) == 0 {
+		return nil, fmt.Errorf("corpus '%s' is too short for order %d", path, order)
+	}
+	return m, nil
+}
+
+// sentence starts where a corpus sentence starts and follows the chain until
+// a sentence ends after at least min words, or max words are reached. max
+// is at least the order.
+func (m *markov) sentence(r *rand.Rand, min, max int) []string {
+	words := append(make([]string, 0, max), m.starts[r.IntN(len(m.starts))]...)
+	for len(words) < max {
+		if len(words) >= min && endsSentence(words[len(words)-1]) {
+			return words
+		}
+		next := m.next[key(words[len(words)-m.order:])]
+		if len(next) == 0 {
+			break
+		}
+		words = append(words, next[r.IntN(len(next))])
+	}
+	// Cut short: end the last word like a sentence.
+	last := strings.TrimRightFunc(words[len(words)-1], unicode.IsPunct)
+	if last == "" {
+		last = words[len(words)-1]
+	}
+	words[len(words)-1] = last + "."
+	return words
+}
+
+// key joins a run of words into a map key.
+func key(words []string) string {
+	return strings.Join(words, "\x00")
+}
+
+// endsSentence reports whether a word ends a sentence, allowing for closing
+// quotes and brackets after the punctuation.
+func endsSentence(word string) bool {
+	word = strings.TrimRight(word, `"')]»”’`)
+	return strings.HasSuffix(word, ".") || strings.HasSuffix(word, "!") || strings.HasSuffix(word, "?")
+}
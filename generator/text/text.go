@@ -0,0 +1,308 @@
+package text
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"likha/expression"
+	"likha/generator/types"
+	"likha/util"
+)
+
+// source writes the sentences of a text.
+type source interface {
+	sentence(r *rand.Rand, min, max int) []string
+}
+
+// span is an inclusive range of counts.
+type span struct{ min, max int }
+
+func (s span) draw(r *rand.Rand) int {
+	return s.min + r.IntN(s.max-s.min+1)
+}
+
+// TextGenerator writes free text: paragraphs of sentences of words, from
+// lorem ipsum or a Markov chain trained on a corpus, with optional hashtags,
+// mentions and URLs. Paragraphs are separated by a blank line.
+type TextGenerator struct {
+	source     source
+	words      span // Per sentence
+	sentences  span // Per paragraph
+	paragraphs span
+	maxLength  int // In characters; 0 for no limit
+	hashtags   *injection
+	mentions   *injection
+	urls       *injection
+}
+
+// injection adds a number of extra words to the text, taken from a list of
+// values or rendered from a template that may reference other fields.
+type injection struct {
+	count    span
+	values   []string
+	template *expression.Template
+	prefix   string // Added to values that lack it
+}
+
+// New creates a TextGenerator.
+func New(settings map[string]interface{}) (types.Generator, error) {
+	g := &TextGenerator{}
+	var err error
+	if g.words, err = spanSetting(settings, "text", "min_words", "max_words", 5, 15, 1); err != nil {
+		return nil, err
+	}
+	if g.sentences, err = spanSetting(settings, "text", "min_sentences", "max_sentences", 1, 3, 1); err != nil {
+		return nil, err
+	}
+	if g.paragraphs, err = spanSetting(settings, "text", "min_paragraphs", "max_paragraphs", 1, 1, 1); err != nil {
+		return nil, err
+	}
+
+	switch model, _ := settings["model"].(string); model {
+	case "", "lorem":
+		g.source = lorem{}
+	case "markov":
+		corpus, _ := settings["corpus"].(string)
+		if corpus == "" {
+			return nil, fmt.Errorf("text model markov requires 'corpus', the path of a text file")
+		}
+		order := 2
+		if v, ok := settings["order"]; ok {
+			order, ok = util.InterfaceToInt(v)
+			if !ok || order < 1 || order > 3 {
+				return nil, fmt.Errorf("text 'order' must be 1, 2 or 3")
+			}
+		}
+		if g.words.max < order {
+			return nil, fmt.Errorf("text 'max_words' must be at least the 'order' of the model")
+		}
+		if g.source, err = newMarkov(corpus, order); err != nil {
+			return nil, fmt.Errorf("text: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("text 'model' must be lorem or markov, got '%s'", model)
+	}
+
+	if v, ok := settings["max_length"]; ok {
+		g.maxLength, ok = util.InterfaceToInt(v)
+		if !ok || g.maxLength < 1 {
+			return nil, fmt.Errorf("text 'max_length' must be a positive number of characters")
+		}
+	}
+	if g.hashtags, err = newInjection(settings, "hashtags", "#", 3, ""); err != nil {
+		return nil, err
+	}
+	if g.mentions, err = newInjection(settings, "mentions", "@", 2, ""); err != nil {
+		return nil, err
+	}
+	if g.mentions != nil && g.mentions.values == nil && g.mentions.template == nil {
+		return nil, fmt.Errorf("text 'mentions' needs 'values' or a 'template' such as \"#username\"")
+	}
+	if g.urls, err = newInjection(settings, "urls", "", 1, "https://example.com/$random_string(8)"); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// spanSetting reads a range of counts from the lo and hi keys. An upper
+// bound left at its default is raised to a larger lower bound.
+func spanSetting(settings map[string]interface{}, owner, lo, hi string, min, max, floor int) (span, error) {
+	s := span{min, max}
+	for _, bound := range []struct {
+		key string
+		dst *int
+	}{{lo, &s.min}, {hi, &s.max}} {
+		if v, ok := settings[bound.key]; ok {
+			n, ok := util.InterfaceToInt(v)
+			if !ok || n < floor {
+				return s, fmt.Errorf("%s '%s' must be a number of at least %d", owner, bound.key, floor)
+			}
+			*bound.dst = n
+		}
+	}
+	if _, ok := settings[hi]; !ok && s.max < s.min {
+		s.max = s.min
+	}
+	if s.min > s.max {
+		return s, fmt.Errorf("%s '%s' cannot be greater than '%s'", owner, lo, hi)
+	}
+	return s, nil
+}
+
+// newInjection reads the hashtags, mentions or urls setting: a mapping with
+// min, max and either values or a template. It returns nil when the setting
+// is absent.
+func newInjection(settings map[string]interface{}, name, prefix string, max int, template string) (*injection, error) {
+	v, ok := settings[name]
+	if !ok {
+		return nil, nil
+	}
+	s, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("text '%s' must be a mapping with min, max and values or a template", name)
+	}
+	in := &injection{prefix: prefix}
+	var err error
+	if in.count, err = spanSetting(s, "text '"+name+"'", "min", "max", 0, max, 0); err != nil {
+		return nil, err
+	}
+	if list, ok := s["values"].([]interface{}); ok {
+		for _, item := range list {
+			if str := fmt.Sprint(item); str != "" {
+				in.values = append(in.values, str)
+			}
+		}
+		if len(in.values) == 0 {
+			return nil, fmt.Errorf("text '%s' 'values' must not be empty", name)
+		}
+	} else if _, ok := s["values"]; ok {
+		return nil, fmt.Errorf("text '%s' 'values' must be a list", name)
+	}
+	if t, ok := s["template"].(string); ok {
+		template = t
+	}
+	if in.values == nil && template != "" {
+		if in.template, err = expression.Compile(template); err != nil {
+			return nil, fmt.Errorf("text '%s' has an invalid template '%s': %w", name, template, err)
+		}
+	}
+	return in, nil
+}
+
+// Generate returns the text of the current record.
+func (g *TextGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	r := ctx.Rand
+	paragraphs := make([][]string, g.paragraphs.draw(r))
+	for p := range paragraphs {
+		for range g.sentences.draw(r) {
+			paragraphs[p] = append(paragraphs[p], g.source.sentence(r, g.words.min, g.words.max)...)
+		}
+	}
+
+	var tags []string
+	if g.hashtags != nil {
+		var err error
+		if tags, err = g.hashtags.draw(ctx, row, paragraphs); err != nil {
+			return nil, err
+		}
+	}
+	for _, in := range []*injection{g.mentions, g.urls} {
+		if in == nil {
+			continue
+		}
+		words, err := in.draw(ctx, row, nil)
+		if err != nil {
+			return nil, err
+		}
+		// Each goes between two words of a random paragraph.
+		for _, w := range words {
+			p := r.IntN(len(paragraphs))
+			i := r.IntN(len(paragraphs[p]) + 1)
+			paragraphs[p] = append(paragraphs[p][:i], append([]string{w}, paragraphs[p][i:]...)...)
+		}
+	}
+	return g.render(paragraphs, tags), nil
+}
+
+// draw returns the words to inject. Hashtags without values or a template
+// are taken from the words of the text itself.
+func (in *injection) draw(ctx *types.Context, row map[string]interface{}, paragraphs [][]string) ([]string, error) {
+	r := ctx.Rand
+	n := in.count.draw(r)
+	if n == 0 {
+		return nil, nil
+	}
+	var candidates []string
+	if in.values == nil && in.template == nil {
+		seen := make(map[string]bool)
+		for _, words := range paragraphs {
+			for _, w := range words {
+				w = strings.ToLower(strings.TrimFunc(w, unicode.IsPunct))
+				if utf8.RuneCountInString(w) >= 5 && !seen[w] && strings.IndexFunc(w, func(c rune) bool { return !unicode.IsLetter(c) }) < 0 {
+					seen[w] = true
+					candidates = append(candidates, w)
+				}
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, nil
+		}
+	}
+	words := make([]string, 0, n)
+	for range n {
+		var w string
+		switch {
+		case in.template != nil:
+			s, err := in.template.Evaluate(ctx, row)
+			if err != nil {
+				return nil, fmt.Errorf("text template '%s': %w", in.template, err)
+			}
+			w = s
+		case in.values != nil:
+			w = in.values[r.IntN(len(in.values))]
+		default:
+			w = candidates[r.IntN(len(candidates))]
+		}
+		w = strings.Join(strings.Fields(w), "")
+		if w == "" || w == in.prefix {
+			continue
+		}
+		if !strings.HasPrefix(w, in.prefix) {
+			w = in.prefix + w
+		}
+		words = append(words, w)
+	}
+	return words, nil
+}
+
+// render joins the paragraphs and appends the hashtags. With a maximum
+// length, the text is cut at a word boundary so that the hashtags still fit.
+func (g *TextGenerator) render(paragraphs [][]string, tags []string) string {
+	tail := ""
+	if len(tags) > 0 {
+		tail = " " + strings.Join(tags, " ")
+	}
+	limit := g.maxLength
+	if limit > 0 {
+		if utf8.RuneCountInString(tail) >= limit {
+			tail = ""
+		}
+		limit -= utf8.RuneCountInString(tail)
+	}
+
+	var b strings.Builder
+	n := 0
+write:
+	for p, words := range paragraphs {
+		for i, w := range words {
+			sep := " "
+			switch {
+			case p == 0 && i == 0:
+				sep = ""
+			case i == 0:
+				sep = "\n\n"
+			}
+			add := utf8.RuneCountInString(sep) + utf8.RuneCountInString(w)
+			if limit > 0 && n+add > limit {
+				if n == 0 {
+					// A single word longer than the limit is cut within the word.
+					b.WriteString(string([]rune(w)[:limit]))
+				}
+				break write
+			}
+			b.WriteString(sep)
+			b.WriteString(w)
+			n += add
+		}
+	}
+	return strings.TrimRight(b.String(), ",;:") + tail
+}
+
+// capitalize upper-cases the first letter of a word.
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + word[size:]
+}
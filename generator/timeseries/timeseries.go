@@ -0,0 +1,295 @@
+package timeseries
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"likha/generator/types"
+	"likha/util"
+	"likha/value"
+)
+
+const (
+	hour = 3600.0
+	day  = 24 * hour
+	week = 7 * day
+)
+
+// hourlyPresets and weeklyPresets are named rate profiles. Weights are
+// relative; they are scaled so that the average hour has the base rate.
+var hourlyPresets = map[string][]float64{
+	"flat":     {1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+	"business": {0.1, 0.1, 0.1, 0.1, 0.1, 0.15, 0.3, 0.6, 1, 1.6, 1.8, 1.8, 1.5, 1.7, 1.8, 1.7, 1.4, 1, 0.6, 0.4, 0.3, 0.2, 0.15, 0.1},
+	"evening":  {0.4, 0.3, 0.2, 0.15, 0.15, 0.2, 0.4, 0.6, 0.8, 0.9, 1, 1.1, 1.2, 1.1, 1, 1, 1.1, 1.3, 1.6, 1.9, 2, 1.8, 1.3, 0.8},
+}
+
+var weeklyPresets = map[string][]float64{
+	"flat":     {1, 1, 1, 1, 1, 1, 1},
+	"workweek": {1, 1, 1, 1, 1, 0.3, 0.3},
+	"weekend":  {0.8, 0.8, 0.8, 0.8, 1, 1.4, 1.4},
+}
+
+// TimeseriesGenerator places record i at the time by which i records are
+// expected, given the rate. The expected count only grows with time, so
+// timestamps never decrease, and each one is computed from its index alone:
+// any worker can generate any record.
+type TimeseriesGenerator struct {
+	start     time.Time
+	rate      float64 // Records per second in an average hour, before growth
+	jitter    float64 // Share of a gap by which a record may be late
+	growth    float64 // Rate increase per second, as a share of rate
+	offset    float64 // Seconds from the start of the week (Monday 00:00 UTC) to start
+	weights   [168]float64
+	cum       [169]float64 // Integral of the weights from the start of the week to each hour
+	mom       [169]float64 // Integral of v·weight(v) from the start of the week to each hour
+	bursts    *bursts
+	precision time.Duration
+}
+
+// bursts raise the rate by a factor for one window of a given length in
+// every period. Each window starts at a random point of its period.
+type bursts struct {
+	every, length float64 // Seconds
+	extra         float64 // Additional records per burst
+}
+
+// burstSalt separates burst placement from other uses of the field seed.
+const burstSalt = 0x6275727374730000
+
+// New creates a TimeseriesGenerator.
+func New(settings map[string]interface{}) (types.Generator, error) {
+	g := &TimeseriesGenerator{precision: time.Second}
+
+	g.start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if v, ok := settings["start"]; ok {
+		s, _ := v.(string)
+		t, _, err := value.ParseTime(s)
+		if err != nil {
+			return nil, fmt.Errorf("timeseries 'start': %w", err)
+		}
+		g.start = t.UTC()
+	}
+
+	interval := time.Minute
+	if v, ok := settings["interval"]; ok {
+		d, err := duration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("timeseries 'interval' must be a positive duration such as 30s or 5m")
+		}
+		interval = d
+	}
+	g.rate = 1 / interval.Seconds()
+
+	if v, ok := settings["jitter"]; ok {
+		f, ok := util.InterfaceToFloat64(v)
+		if !ok || f < 0 || f > 1 {
+			return nil, fmt.Errorf("timeseries 'jitter' must be between 0 and 1")
+		}
+		g.jitter = f
+	}
+	if v, ok := settings["growth"]; ok {
+		f, ok := util.InterfaceToFloat64(v)
+		if !ok || f < 0 {
+			return nil, fmt.Errorf("timeseries 'growth' must be a non-negative number")
+		}
+		g.growth = f / day
+	}
+	if v, ok := settings["precision"]; ok {
+		d, err := duration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("timeseries 'precision' must be a duration such as 1s or 1ms")
+		}
+		g.precision = d
+	}
+
+	hourly, err := profile(settings, "hourly", 24, hourlyPresets)
+	if err != nil {
+		return nil, err
+	}
+	weekly, err := profile(settings, "weekly", 7, weeklyPresets)
+	if err != nil {
+		return nil, err
+	}
+	var total float64
+	for h := range g.weights {
+		g.weights[h] = weekly[h/24] * hourly[h%24]
+		total += g.weights[h]
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("timeseries 'hourly' and 'weekly' leave no hour with a non-zero rate")
+	}
+	for h := range g.weights {
+		g.weights[h] *= 168 / total
+		lo, hi := float64(h)*hour, float64(h+1)*hour
+		g.cum[h+1] = g.cum[h] + g.weights[h]*hour
+		g.mom[h+1] = g.mom[h] + g.weights[h]*(hi*hi-lo*lo)/2
+	}
+	weekday := (int(g.start.Weekday()) + 6) % 7
+	midnight := time.Date(g.start.Year(), g.start.Month(), g.start.Day(), 0, 0, 0, 0, time.UTC)
+	g.offset = float64(weekday)*day + g.start.Sub(midnight).Seconds()
+
+	if v, ok := settings["bursts"]; ok {
+		b, err := newBursts(v, g.rate)
+		if err != nil {
+			return nil, err
+		}
+		g.bursts = b
+	}
+	return g, nil
+}
+
+// newBursts reads the 'bursts' setting: every, duration and factor.
+func newBursts(v interface{}, rate float64) (*bursts, error) {
+	s, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("timeseries 'bursts' must be a mapping with every, duration and factor")
+	}
+	every, err := duration(s["every"])
+	if err != nil || every <= 0 {
+		return nil, fmt.Errorf("timeseries 'bursts' needs 'every', a positive duration such as 6h")
+	}
+	length, err := duration(s["duration"])
+	if err != nil || length <= 0 || length >= every {
+		return nil, fmt.Errorf("timeseries 'bursts' needs 'duration', a positive duration shorter than 'every'")
+	}
+	factor := 5.0
+	if f, ok := s["factor"]; ok {
+		factor, ok = util.InterfaceToFloat64(f)
+		if !ok || factor < 1 {
+			return nil, fmt.Errorf("timeseries 'bursts' 'factor' must be a number of at least 1")
+		}
+	}
+	return &bursts{
+		every:  every.Seconds(),
+		length: length.Seconds(),
+		extra:  (factor - 1) * rate * length.Seconds(),
+	}, nil
+}
+
+// profile reads an hourly or weekly rate profile: a preset name or a list of n weights.
+func profile(settings map[string]interface{}, name string, n int, presets map[string][]float64) ([]float64, error) {
+	switch v := settings[name].(type) {
+	case nil:
+		return presets["flat"], nil
+	case string:
+		if p, ok := presets[v]; ok {
+			return p, nil
+		}
+	case []interface{}:
+		if len(v) != n {
+			break
+		}
+		weights := make([]float64, n)
+		for i, w := range v {
+			f, ok := util.InterfaceToFloat64(w)
+			if !ok || f < 0 {
+				return nil, fmt.Errorf("timeseries '%s' weights must be non-negative numbers", name)
+			}
+			weights[i] = f
+		}
+		return weights, nil
+	}
+	names := make([]string, 0, len(presets))
+	for p := range presets {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("timeseries '%s' must be one of %s or a list of %d weights", name, strings.Join(names, ", "), n)
+}
+
+// duration reads a duration setting, e.g. "90s" or "1d".
+func duration(v interface{}) (time.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("not a duration")
+	}
+	return util.ParseDuration(s)
+}
+
+// Generate returns the timestamp of the current record.
+func (g *TimeseriesGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	x := float64(ctx.Index)
+	if g.jitter > 0 {
+		x += g.jitter * ctx.Rand.Float64()
+	}
+	t := g.timeOf(ctx.Seed, x)
+	return g.start.Add(time.Duration(t * float64(time.Second))).Truncate(g.precision), nil
+}
+
+// timeOf returns the number of seconds after start by which x records are
+// expected, by bisection.
+func (g *TimeseriesGenerator) timeOf(seed int64, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	lo, hi := 0.0, x/g.rate
+	for g.expected(seed, hi) < x {
+		lo, hi = hi, hi*2
+	}
+	for hi-lo > 1e-6 {
+		mid := (lo + hi) / 2
+		if mid == lo || mid == hi {
+			break
+		}
+		if g.expected(seed, mid) < x {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
+// expected returns the expected number of records in the first t seconds.
+func (g *TimeseriesGenerator) expected(seed int64, t float64) float64 {
+	u0, u1 := g.offset, g.offset+t
+	weighted := g.integral(u1) - g.integral(u0)
+	n := weighted
+	if g.growth > 0 {
+		// The rate grows linearly with the time since start, u - u0.
+		n += g.growth * (g.moment(u1) - g.moment(u0) - u0*weighted)
+	}
+	n *= g.rate
+	if g.bursts != nil {
+		n += g.bursts.expected(seed, t)
+	}
+	return n
+}
+
+// integral returns the integral of the hourly weights over the first u
+// seconds after the start of a week.
+func (g *TimeseriesGenerator) integral(u float64) float64 {
+	k, rem, h := split(u)
+	return k*g.cum[168] + g.cum[h] + g.weights[h]*(rem-float64(h)*hour)
+}
+
+// moment returns the integral of v·weight(v) over the first u seconds after
+// the start of a week.
+func (g *TimeseriesGenerator) moment(u float64) float64 {
+	k, rem, h := split(u)
+	lo := float64(h) * hour
+	cum := g.cum[h] + g.weights[h]*(rem-lo)
+	mom := g.mom[h] + g.weights[h]*(rem*rem-lo*lo)/2
+	// Each full week k contributes its own moment plus its weight times the week's offset.
+	return k*g.mom[168] + week*g.cum[168]*k*(k-1)/2 + k*week*cum + mom
+}
+
+// split divides u into full weeks, the remainder and the hour of the remainder.
+func split(u float64) (weeks, rem float64, h int) {
+	weeks = math.Floor(u / week)
+	rem = u - weeks*week
+	h = min(int(rem/hour), 167)
+	return weeks, rem, h
+}
+
+// expected returns the number of extra records of the bursts in the first t seconds.
+func (b *bursts) expected(seed int64, t float64) float64 {
+	k := math.Floor(t / b.every)
+	u := float64(util.Mix64(uint64(seed)^util.Mix64(uint64(k))^burstSalt)>>11) / (1 << 53)
+	start := k*b.every + u*(b.every-b.length)
+	partial := math.Min(math.Max((t-start)/b.length, 0), 1)
+	return b.extra * (k + partial)
+}
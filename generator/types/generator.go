@@ -2,13 +2,53 @@ package types
 
 import (
 	"fmt"
+	"math/rand/v2"
 
 	"likha/config"
+	"likha/util"
 )
 
 // Generator is the interface that all value generators must implement.
+// A single instance is shared by every worker, so implementations must be
+// safe for concurrent use: keep them immutable after construction and draw
+// all randomness from ctx.Rand. A value then depends only on the seed and
+// the record index, never on scheduling.
 type Generator interface {
-	Generate(row map[string]interface{}) (interface{}, error)
+	Generate(ctx *Context, row map[string]interface{}) (interface{}, error)
+}
+
+// Context carries the per-record state passed to every Generate call.
+type Context struct {
+	// Index is the zero-based index of the record being generated.
+	Index int64
+	// Rand is a random source derived from the field seed and Index.
+	Rand *rand.Rand
+	// Seed is the field seed. Generators whose values depend on other
+	// records, such as a random walk, derive their randomness from it.
+	Seed int64
+
+	src *rand.PCG
+}
+
+// NewContext creates a Context. A Context is not safe for concurrent use;
+// each worker goroutine should own one and Reset it for every field.
+func NewContext() *Context {
+	src := rand.NewPCG(0, 0)
+	return &Context{Rand: rand.New(src), src: src}
+}
+
+// Reset points the context at the given record and reseeds Rand from
+// seed and index, so the same pair always yields the same stream.
+func (c *Context) Reset(index int64, seed int64) {
+	c.Index = index
+	c.Seed = seed
+	c.src.Seed(util.Mix64(uint64(seed)), util.Mix64(uint64(seed)^util.Mix64(uint64(index))))
+}
+
+// RowKey returns the key under which an entity is stored in the row while a
+// record is generated. The prefix keeps it apart from field names.
+func RowKey(name string) string {
+	return "@" + name
 }
 
 // GeneratorFactory creates a Generator based on the provided configuration.
@@ -2,16 +2,20 @@ package csv
 
 import (
 	"encoding/csv"
-	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	"likha/output/types"
+	"likha/value"
 )
 
 // CSVWriter writes data in CSV format.
 type CSVWriter struct {
-	writer  *csv.Writer
-	headers []string
+	writer    *csv.Writer
+	headers   []string
+	paths     [][]string // Path of each column into nested objects and arrays
+	nullValue string     // Written for null values; empty by default
 }
 
 // New creates a new CSVWriter.
@@ -22,11 +26,22 @@ func New(w io.Writer, settings map[string]interface{}) (types.Writer, error) {
 		writer.Comma = rune(delim[0])
 	}
 
-	return &CSVWriter{writer: writer}, nil
+	cw := &CSVWriter{writer: writer}
+	if null, ok := settings["null_value"].(string); ok {
+		cw.nullValue = null
+	}
+	return cw, nil
 }
 
-// WriteHeader writes the CSV header row.
-func (w *CSVWriter) WriteHeader(headers []string) error {
+// WriteHeader writes the CSV header row. Nested objects and arrays are
+// flattened into one column per leaf, named by its dotted path such as
+// customer.address.city or items.0.sku.
+func (w *CSVWriter) WriteHeader(columns []types.Column) error {
+	w.paths = flatten(nil, columns)
+	headers := make([]string, len(w.paths))
+	for i, p := range w.paths {
+		headers[i] = strings.Join(p, ".")
+	}
 	w.headers = headers
 	includeHeaders := true
 
@@ -42,13 +57,62 @@ func (w *CSVWriter) WriteHeader(headers []string) error {
 
 // WriteRow writes a single row to the CSV file.
 func (w *CSVWriter) WriteRow(row map[string]interface{}) error {
-	record := make([]string, len(w.headers))
-	for i, h := range w.headers {
-		record[i] = fmt.Sprintf("%v", row[h])
+	record := make([]string, len(w.paths))
+	for i, p := range w.paths {
+		v := lookup(row, p)
+		if v == nil {
+			record[i] = w.nullValue
+			continue
+		}
+		record[i] = value.Text(v)
 	}
 	return w.writer.Write(record)
 }
 
+// flatten lists the paths of all leaf columns below prefix.
+func flatten(prefix []string, columns []types.Column) [][]string {
+	var paths [][]string
+	for _, c := range columns {
+		path := append(append([]string(nil), prefix...), c.Name)
+		switch {
+		case c.Array:
+			for i := 0; i < c.MaxItems; i++ {
+				item := append(append([]string(nil), path...), strconv.Itoa(i))
+				if c.Object {
+					paths = append(paths, flatten(item, c.Children)...)
+				} else {
+					paths = append(paths, item)
+				}
+			}
+		case c.Object:
+			paths = append(paths, flatten(path, c.Children)...)
+		default:
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// lookup follows a column path through nested maps and lists. Missing
+// entries, such as items past the end of a shorter array, are nil.
+func lookup(v interface{}, path []string) interface{} {
+	for _, key := range path {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			v = node[key]
+		case []interface{}:
+			i, _ := strconv.Atoi(key)
+			if i >= len(node) {
+				return nil
+			}
+			v = node[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
 // Close flushes the writer.
 func (w *CSVWriter) Close() error {
 	w.writer.Flush()
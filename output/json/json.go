@@ -28,7 +28,7 @@ func New(w io.Writer, settings map[string]interface{}) (types.Writer, error) {
 }
 
 // WriteHeader starts the JSON array.
-func (w *JSONWriter) WriteHeader(headers []string) error {
+func (w *JSONWriter) WriteHeader(columns []types.Column) error {
 	_, err := w.writer.Write([]byte("[\n"))
 	return err
 }
@@ -0,0 +1,136 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"likha/config"
+	"likha/output/types"
+
+	"gopkg.in/yaml.v3"
+)
+
+// write renders rows with the writer of the given type and returns the output.
+func write(t *testing.T, typ string, settings map[string]interface{}, columns []types.Column, rows ...map[string]interface{}) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&config.OutputConfig{Type: typ, Settings: settings}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeader(columns); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestNullAndEmptyValues(t *testing.T) {
+	columns := []types.Column{{Name: "id"}, {Name: "missing"}, {Name: "blank"}}
+	row := map[string]interface{}{"id": int64(1), "missing": nil, "blank": ""}
+
+	tests := []struct {
+		typ      string
+		settings map[string]interface{}
+		want     []string
+	}{
+		{"csv", nil, []string{"id,missing,blank\n1,,\n"}},
+		{"csv", map[string]interface{}{"null_value": `\N`}, []string{"id,missing,blank\n1,\\N,\n"}},
+		{"json", nil, []string{`"missing":null`, `"blank":""`}},
+		{"xml", nil, []string{`<missing xsi:nil="true"></missing>`, `<blank></blank>`}},
+		{"yaml", nil, []string{"missing: ~\n", "blank: \"\"\n"}},
+	}
+	for _, tt := range tests {
+		out := write(t, tt.typ, tt.settings, columns, row)
+		for _, want := range tt.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s %v: output lacks %q:\n%s", tt.typ, tt.settings, want, out)
+			}
+		}
+	}
+}
+
+// nestedColumns and nestedRow hold an object, an array of objects and an
+// array of scalars, each array shorter than its maximum.
+var nestedColumns = []types.Column{
+	{Name: "id"},
+	{Name: "customer", Object: true, Children: []types.Column{
+		{Name: "tier"},
+		{Name: "address", Object: true, Children: []types.Column{{Name: "city"}}},
+	}},
+	{Name: "items", Array: true, Object: true, MaxItems: 3, Children: []types.Column{{Name: "sku"}, {Name: "qty"}}},
+	{Name: "tags", Array: true, MaxItems: 2},
+}
+
+var nestedRow = map[string]interface{}{
+	"id": int64(1),
+	"customer": map[string]interface{}{
+		"tier":    "gold",
+		"address": map[string]interface{}{"city": "Lyon"},
+	},
+	"items": []interface{}{
+		map[string]interface{}{"sku": "A1", "qty": int64(2)},
+		map[string]interface{}{"sku": "B2", "qty": int64(5)},
+	},
+	"tags": []interface{}{"new"},
+}
+
+func TestNestedCSV(t *testing.T) {
+	out := write(t, "csv", nil, nestedColumns, nestedRow)
+	want := "id,customer.tier,customer.address.city,items.0.sku,items.0.qty,items.1.sku,items.1.qty,items.2.sku,items.2.qty,tags.0,tags.1\n" +
+		"1,gold,Lyon,A1,2,B2,5,,,new,\n"
+	if out != want {
+		t.Fatalf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestNestedStructured(t *testing.T) {
+	want := map[string]interface{}{
+		"id":       1,
+		"customer": map[string]interface{}{"tier": "gold", "address": map[string]interface{}{"city": "Lyon"}},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "A1", "qty": 2},
+			map[string]interface{}{"sku": "B2", "qty": 5},
+		},
+		"tags": []interface{}{"new"},
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(write(t, "json", nil, nestedColumns, nestedRow)), &rows); err != nil {
+		t.Fatal(err)
+	}
+	// JSON numbers decode as float64; compare through a round trip of want.
+	var wantJSON map[string]interface{}
+	data, _ := json.Marshal(want)
+	json.Unmarshal(data, &wantJSON)
+	if len(rows) != 1 || !reflect.DeepEqual(rows[0], wantJSON) {
+		t.Fatalf("json: got %v, want %v", rows, wantJSON)
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal([]byte(write(t, "yaml", nil, nestedColumns, nestedRow)), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("yaml: got %v, want %v", doc, want)
+	}
+
+	xml := regexp.MustCompile(`>\s+<`).ReplaceAllString(write(t, "xml", nil, nestedColumns, nestedRow), "><")
+	wantXML := "<row><id>1</id><customer><tier>gold</tier><address><city>Lyon</city></address></customer>" +
+		"<items><item><sku>A1</sku><qty>2</qty></item><item><sku>B2</sku><qty>5</qty></item></items>" +
+		"<tags><item>new</item></tags></row>"
+	if !strings.Contains(xml, wantXML) {
+		t.Fatalf("xml: got\n%s\nwant it to contain\n%s", xml, wantXML)
+	}
+}
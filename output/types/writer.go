@@ -4,13 +4,25 @@ import "io"
 
 // Writer is the interface that all data writers must implement.
 type Writer interface {
-	// WriteHeader writes the header of the file, if applicable.
-	WriteHeader(headers []string) error
+	// WriteHeader writes the header of the file, if applicable, and
+	// records the columns that every following row holds.
+	WriteHeader(columns []Column) error
 	// WriteRow writes a single row of data.
 	WriteRow(row map[string]interface{}) error
 	// Close finalizes the writing process and closes the underlying writer.
 	Close() error
 }
 
+// Column describes a field of the output. Array columns hold lists of
+// values; object columns (or, for arrays, their items) are maps whose
+// fields are described by Children.
+type Column struct {
+	Name     string
+	Array    bool
+	Object   bool
+	Children []Column
+	MaxItems int // Largest possible array length
+}
+
 // WriterFactory creates a Writer based on the provided configuration.
 type WriterFactory func(w io.Writer, settings map[string]interface{}) (Writer, error)
@@ -2,17 +2,21 @@ package xml
 
 import (
 	"encoding/xml"
-	"fmt"
 	"io"
 
 	"likha/output/types"
+	"likha/value"
 )
 
+// xsiNamespace is the XML Schema instance namespace, which defines xsi:nil.
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
 // XMLWriter writes data in XML format.
 type XMLWriter struct {
 	writer   io.Writer
 	encoder  *xml.Encoder
 	rootNode string
+	columns  []types.Column
 }
 
 // New creates a new XMLWriter.
@@ -30,12 +34,17 @@ func New(w io.Writer, settings map[string]interface{}) (types.Writer, error) {
 }
 
 // WriteHeader writes the XML header and root element.
-func (w *XMLWriter) WriteHeader(headers []string) error {
+func (w *XMLWriter) WriteHeader(columns []types.Column) error {
+	w.columns = columns
 	_, err := w.writer.Write([]byte(xml.Header))
 	if err != nil {
 		return err
 	}
-	return w.encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: w.rootNode}})
+	// Declare the XML Schema instance namespace for xsi:nil on null values.
+	return w.encoder.EncodeToken(xml.StartElement{
+		Name: xml.Name{Local: w.rootNode},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace}},
+	})
 }
 
 // WriteRow writes a single row as an XML element.
@@ -45,20 +54,54 @@ func (w *XMLWriter) WriteRow(row map[string]interface{}) error {
 		return err
 	}
 
-	for key, val := range row {
-		elemStart := xml.StartElement{Name: xml.Name{Local: key}}
-		if err := w.encoder.EncodeToken(elemStart); err != nil {
-			return err
-		}
-		if err := w.encoder.EncodeToken(xml.CharData(fmt.Sprintf("%v", val))); err != nil {
-			return err
-		}
-		if err := w.encoder.EncodeToken(elemStart.End()); err != nil {
+	// Follow the header order so the output is stable across runs.
+	if err := w.writeFields(row, w.columns); err != nil {
+		return err
+	}
+
+	return w.encoder.EncodeToken(start.End())
+}
+
+// writeFields writes one element per column, in column order.
+func (w *XMLWriter) writeFields(obj map[string]interface{}, columns []types.Column) error {
+	for _, c := range columns {
+		if err := w.writeElement(c.Name, obj[c.Name], c); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	return w.encoder.EncodeToken(start.End())
+// writeElement writes a value as an element. Objects become nested
+// elements and arrays a list of <item> elements.
+func (w *XMLWriter) writeElement(name string, val interface{}, c types.Column) error {
+	elemStart := xml.StartElement{Name: xml.Name{Local: name}}
+	if val == nil {
+		elemStart.Attr = []xml.Attr{{Name: xml.Name{Local: "xsi:nil"}, Value: "true"}}
+	}
+	if err := w.encoder.EncodeToken(elemStart); err != nil {
+		return err
+	}
+
+	var err error
+	switch v := val.(type) {
+	case nil:
+	case map[string]interface{}:
+		err = w.writeFields(v, c.Children)
+	case []interface{}:
+		item := types.Column{Object: c.Object, Children: c.Children}
+		for _, e := range v {
+			if err = w.writeElement("item", e, item); err != nil {
+				break
+			}
+		}
+	default:
+		err = w.encoder.EncodeToken(xml.CharData(value.Text(val)))
+	}
+	if err != nil {
+		return err
+	}
+	return w.encoder.EncodeToken(elemStart.End())
 }
 
 // Close closes the root XML element.
@@ -4,6 +4,7 @@ import (
 	"io"
 
 	"likha/output/types"
+	"likha/value"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,6 +13,7 @@ import (
 type YAMLWriter struct {
 	writer  io.Writer
 	encoder *yaml.Encoder
+	columns []types.Column
 }
 
 // New creates a new YAMLWriter.
@@ -22,14 +24,71 @@ func New(w io.Writer, settings map[string]interface{}) (types.Writer, error) {
 	}, nil
 }
 
-// WriteHeader is a no-op for YAML stream.
-func (w *YAMLWriter) WriteHeader(headers []string) error {
+// WriteHeader records the field order; nothing is written for a YAML stream.
+func (w *YAMLWriter) WriteHeader(columns []types.Column) error {
+	w.columns = columns
 	return nil
 }
 
-// WriteRow writes a single row as a YAML document.
+// WriteRow writes a single row as a YAML document, keeping the field order
+// and rendering null values as ~.
 func (w *YAMLWriter) WriteRow(row map[string]interface{}) error {
-	return w.encoder.Encode(row)
+	doc, err := mappingNode(row, w.columns)
+	if err != nil {
+		return err
+	}
+	return w.encoder.Encode(doc)
+}
+
+// mappingNode converts an object to a YAML mapping with its fields in column order.
+func mappingNode(obj map[string]interface{}, columns []types.Column) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, c := range columns {
+		valNode, err := valueNode(obj[c.Name], c)
+		if err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: c.Name}, valNode)
+	}
+	return node, nil
+}
+
+// valueNode converts a generated value to a YAML node. Decimals keep their
+// scale, dates are unquoted and bytes are base64 with a !!binary tag.
+func valueNode(val interface{}, c types.Column) (*yaml.Node, error) {
+	if val == nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "~"}, nil
+	}
+	switch v := val.(type) {
+	case value.Decimal:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: v.String()}, nil
+	case value.Date:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: v.String()}, nil
+	case []byte:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!binary", Value: value.Text(v)}, nil
+	case map[string]interface{}:
+		if c.Object {
+			return mappingNode(v, c.Children)
+		}
+	case []interface{}:
+		if c.Array {
+			seq := &yaml.Node{Kind: yaml.SequenceNode}
+			item := types.Column{Object: c.Object, Children: c.Children}
+			for _, e := range v {
+				n, err := valueNode(e, item)
+				if err != nil {
+					return nil, err
+				}
+				seq.Content = append(seq.Content, n)
+			}
+			return seq, nil
+		}
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(val); err != nil {
+		return nil, err
+	}
+	return node, nil
 }
 
 // Close closes the YAML encoder.
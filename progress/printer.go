@@ -0,0 +1,108 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Printer reports progress as plain-text or JSON lines, for runs without a terminal UI.
+type Printer struct {
+	w         io.Writer
+	json      bool
+	total     int64
+	startTime time.Time
+}
+
+// printerEvent is the JSON representation of a progress line.
+type printerEvent struct {
+	Event         string  `json:"event"`
+	Current       int64   `json:"current"`
+	Total         int64   `json:"total"`
+	Percent       float64 `json:"percent"`
+	ElapsedSecs   float64 `json:"elapsed_seconds"`
+	RowsPerSecond float64 `json:"rows_per_second"`
+	Output        string  `json:"output,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+// NewPrinter creates a Printer writing to w. format is "plain" or "json".
+func NewPrinter(w io.Writer, format string, total int64) (*Printer, error) {
+	switch format {
+	case "", "plain":
+		return &Printer{w: w, total: total, startTime: time.Now()}, nil
+	case "json":
+		return &Printer{w: w, json: true, total: total, startTime: time.Now()}, nil
+	default:
+		return nil, fmt.Errorf("unknown progress format: %s", format)
+	}
+}
+
+// Progress prints a single progress line.
+func (p *Printer) Progress(current int64) {
+	ev := p.event("progress", current)
+	if p.json {
+		p.writeJSON(ev)
+		return
+	}
+	line := fmt.Sprintf("Progress: %s/%s (%.1f%%) | Elapsed: %v | %s rows/s",
+		formatNumber(current), formatNumber(p.total), ev.Percent,
+		p.elapsed().Round(time.Second), formatNumber(int64(ev.RowsPerSecond)))
+	if eta := p.eta(current); eta > 0 {
+		line += fmt.Sprintf(" | ETA: %v", eta.Round(time.Second))
+	}
+	fmt.Fprintln(p.w, line)
+}
+
+// Summary prints the final line of a run: where the data went, or why it stopped.
+func (p *Printer) Summary(current int64, output string, err error) {
+	ev := p.event("done", current)
+	ev.Output = output
+	if err != nil {
+		ev.Event = "error"
+		ev.Error = err.Error()
+	}
+	if p.json {
+		p.writeJSON(ev)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(p.w, "Error after %s/%s records: %v\n", formatNumber(current), formatNumber(p.total), err)
+		return
+	}
+	fmt.Fprintf(p.w, "Generated %s records to %s in %v (%s rows/s)\n",
+		formatNumber(current), output, p.elapsed().Round(time.Millisecond), formatNumber(int64(ev.RowsPerSecond)))
+}
+
+func (p *Printer) event(name string, current int64) printerEvent {
+	elapsed := p.elapsed().Seconds()
+	ev := printerEvent{Event: name, Current: current, Total: p.total, ElapsedSecs: elapsed}
+	if p.total > 0 {
+		ev.Percent = float64(current) / float64(p.total) * 100
+	}
+	if elapsed > 0 {
+		ev.RowsPerSecond = float64(current) / elapsed
+	}
+	return ev
+}
+
+func (p *Printer) writeJSON(ev printerEvent) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(p.w, string(data))
+}
+
+func (p *Printer) elapsed() time.Duration {
+	return time.Since(p.startTime)
+}
+
+func (p *Printer) eta(current int64) time.Duration {
+	if current <= 0 || current >= p.total {
+		return 0
+	}
+	elapsed := p.elapsed()
+	return time.Duration(float64(elapsed) * float64(p.total-current) / float64(current))
+}
@@ -0,0 +1,192 @@
+package regexgen
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// DefaultMaxRepeat bounds the open-ended repetitions *, + and {n,}.
+const DefaultMaxRepeat = 10
+
+// wideClass is the size above which a character class that also contains
+// printable ASCII is narrowed to it, so that [^0-9] or \W yield readable text.
+const wideClass = 1000
+
+// printable is the printable ASCII range used for '.' and wide classes.
+var printable = []rune{0x20, 0x7e}
+
+// Generator produces strings matching a pattern. It is immutable and safe for concurrent use.
+type Generator struct {
+	root *node
+}
+
+type node struct {
+	op       syntax.Op
+	runes    []rune // Literal runes, or class ranges as lo-hi pairs
+	foldCase bool
+	sizes    []int // Cumulative class sizes, aligned with range pairs
+	min, max int   // Repetition bounds
+	sub      []*node
+}
+
+// Compile parses pattern using Go's regexp/syntax (Perl flavour). Open-ended
+// repetitions are capped at maxRepeat extra occurrences. Anchors and word
+// boundaries match without producing output.
+func Compile(pattern string, maxRepeat int) (*Generator, error) {
+	if maxRepeat < 0 {
+		return nil, fmt.Errorf("max_repeat must not be negative")
+	}
+	if err := checkUnsupported(pattern); err != nil {
+		return nil, err
+	}
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	root, err := build(re, maxRepeat)
+	if err != nil {
+		return nil, err
+	}
+	return &Generator{root: root}, nil
+}
+
+// checkUnsupported reports constructs Go's parser rejects with a less helpful message.
+func checkUnsupported(pattern string) error {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			if i+1 < len(pattern) {
+				// \1 to \9 are backreferences unless followed by more octal digits.
+				c := pattern[i+1]
+				octal := i+2 < len(pattern) && pattern[i+2] >= '0' && pattern[i+2] <= '7' && c <= '7'
+				if c >= '1' && c <= '9' && !octal || c == 'k' {
+					return fmt.Errorf("backreferences such as \\%c are not supported", c)
+				}
+			}
+			i++
+		case '(':
+			rest := pattern[i+1:]
+			for _, look := range []string{"?=", "?!", "?<=", "?<!"} {
+				if strings.HasPrefix(rest, look) {
+					return fmt.Errorf("lookaround assertions such as (%s...) are not supported", look)
+				}
+			}
+			if strings.HasPrefix(rest, "?P=") {
+				return fmt.Errorf("backreferences such as (?P=name) are not supported")
+			}
+		}
+	}
+	return nil
+}
+
+func build(re *syntax.Regexp, maxRepeat int) (*node, error) {
+	n := &node{op: re.Op}
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return nil, fmt.Errorf("pattern can never match")
+	case syntax.OpLiteral:
+		n.runes = re.Rune
+		n.foldCase = re.Flags&syntax.FoldCase != 0
+	case syntax.OpCharClass:
+		n.runes = narrow(re.Rune)
+		if len(n.runes) == 0 {
+			return nil, fmt.Errorf("character class %s matches nothing", re)
+		}
+		total := 0
+		for i := 0; i < len(n.runes); i += 2 {
+			total += int(n.runes[i+1]-n.runes[i]) + 1
+			n.sizes = append(n.sizes, total)
+		}
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		n.op = syntax.OpCharClass
+		n.runes = printable
+		n.sizes = []int{int(printable[1]-printable[0]) + 1}
+	case syntax.OpStar:
+		n.min, n.max = 0, maxRepeat
+	case syntax.OpPlus:
+		n.min, n.max = 1, 1+maxRepeat
+	case syntax.OpQuest:
+		n.min, n.max = 0, 1
+	case syntax.OpRepeat:
+		n.min, n.max = re.Min, re.Max
+		if n.max < 0 {
+			n.max = n.min + maxRepeat
+		}
+	}
+	for _, sub := range re.Sub {
+		s, err := build(sub, maxRepeat)
+		if err != nil {
+			return nil, err
+		}
+		n.sub = append(n.sub, s)
+	}
+	return n, nil
+}
+
+// narrow restricts very wide classes (such as negated ones) to printable ASCII when they include it.
+func narrow(ranges []rune) []rune {
+	total := 0
+	for i := 0; i < len(ranges); i += 2 {
+		total += int(ranges[i+1]-ranges[i]) + 1
+	}
+	if total <= wideClass {
+		return ranges
+	}
+	var ascii []rune
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := max(ranges[i], printable[0]), min(ranges[i+1], printable[1])
+		if lo <= hi {
+			ascii = append(ascii, lo, hi)
+		}
+	}
+	if len(ascii) == 0 {
+		return ranges
+	}
+	return ascii
+}
+
+// Generate returns a random string matching the pattern.
+func (g *Generator) Generate(r *rand.Rand) string {
+	var b strings.Builder
+	g.root.write(r, &b)
+	return b.String()
+}
+
+func (n *node) write(r *rand.Rand, b *strings.Builder) {
+	switch n.op {
+	case syntax.OpLiteral:
+		for _, c := range n.runes {
+			if n.foldCase && r.IntN(2) == 0 {
+				if unicode.IsUpper(c) {
+					c = unicode.ToLower(c)
+				} else {
+					c = unicode.ToUpper(c)
+				}
+			}
+			b.WriteRune(c)
+		}
+	case syntax.OpCharClass:
+		k := r.IntN(n.sizes[len(n.sizes)-1])
+		for i, size := range n.sizes {
+			if k < size {
+				b.WriteRune(n.runes[2*i+1] - rune(size-1-k))
+				break
+			}
+		}
+	case syntax.OpCapture, syntax.OpConcat:
+		for _, s := range n.sub {
+			s.write(r, b)
+		}
+	case syntax.OpAlternate:
+		n.sub[r.IntN(len(n.sub))].write(r, b)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		count := n.min + r.IntN(n.max-n.min+1)
+		for i := 0; i < count; i++ {
+			n.sub[0].write(r, b)
+		}
+	}
+	// Empty matches, anchors and word boundaries produce no output.
+}
@@ -0,0 +1,71 @@
+package regexgen
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateMatches(t *testing.T) {
+	patterns := []string{
+		`[A-Z]{3}-\d{4}-(foo|bar)+`,
+		`SKU-\d{6}`,
+		`^[a-z0-9._%+-]{3,12}@(example|test)\.(com|org)$`,
+		`\w+\s\W?\d*`,
+		`(?i)hello [^0-9]{2,5}`,
+		`colou?r|gr[ae]y`,
+		`[\p{Greek}]{3}`,
+		`a.b.c`,
+		`\bword\b`,
+		`(ab){2,}x*`,
+		`[[:alpha:]][[:digit:]]{2}`,
+		`\x41é\t`,
+	}
+	r := rand.New(rand.NewPCG(7, 8))
+	for _, p := range patterns {
+		g, err := Compile(p, DefaultMaxRepeat)
+		if err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		re := regexp.MustCompile(`^(?:` + p + `)$`)
+		for i := 0; i < 500; i++ {
+			if out := g.Generate(r); !re.MatchString(out) {
+				t.Fatalf("%s generated %q, which does not match", p, out)
+			}
+		}
+	}
+}
+
+func TestMaxRepeat(t *testing.T) {
+	g, err := Compile(`a+b*`, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rand.New(rand.NewPCG(1, 1))
+	for i := 0; i < 500; i++ {
+		out := g.Generate(r)
+		if a := strings.Count(out, "a"); a < 1 || a > 4 || strings.Count(out, "b") > 3 {
+			t.Fatalf("%q exceeds 3 extra repetitions", out)
+		}
+	}
+}
+
+func TestCompileRejectsUnsupported(t *testing.T) {
+	tests := []struct{ pattern, want string }{
+		{`(a)\1`, "backreferences"},
+		{`(?P<x>a)(?P=x)`, "backreferences"},
+		{`foo(?=bar)`, "lookaround"},
+		{`(?<!x)y`, "lookaround"},
+		{`[a-`, "invalid pattern"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.pattern, DefaultMaxRepeat)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) = %v, want an error about %s", tt.pattern, err, tt.want)
+		}
+	}
+	if _, err := Compile(`a*`, -1); err == nil {
+		t.Error("a negative max_repeat should be rejected")
+	}
+}
@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"likha/config"
+	"likha/generator/entity"
+	"likha/generator/types"
+	"likha/util"
+)
+
+// entityField is an entity generated at the start of every record. Its value
+// is only visible to the fields of that record and is never written.
+type entityField struct {
+	name string
+	key  string // Key in the row, see types.RowKey
+	seed int64
+	gen  *entity.EntityGenerator
+}
+
+// newEntities creates the configured entities, plus an implicit one for each
+// 'from' reference that names an entity type instead (e.g. from: address.city).
+// In a child table, 'parent' names the parent record rather than an entity.
+func newEntities(cfg *config.Config, seed int64, child bool) ([]entityField, error) {
+	fieldNames := make(map[string]bool, len(cfg.Fields))
+	for _, f := range cfg.Fields {
+		fieldNames[f.Name] = true
+	}
+
+	var entities []entityField
+	add := func(e config.Entity) error {
+		if fieldNames[e.Name] {
+			return fmt.Errorf("entity '%s' has the same name as a field", e.Name)
+		}
+		if child && e.Name == parentName {
+			return fmt.Errorf("entity name '%s' is reserved in child tables", e.Name)
+		}
+		for _, other := range entities {
+			if other.name == e.Name {
+				return fmt.Errorf("entity '%s' is defined twice", e.Name)
+			}
+		}
+		gen, err := entity.New(e.Type, e.Settings)
+		if err != nil {
+			return fmt.Errorf("entity '%s': %w", e.Name, err)
+		}
+		s := util.DeriveSeed(seed, e.Name)
+		if e.Seed != nil {
+			s = *e.Seed
+		}
+		entities = append(entities, entityField{name: e.Name, key: types.RowKey(e.Name), seed: s, gen: gen})
+		return nil
+	}
+
+	for _, e := range cfg.Entities {
+		if err := add(e); err != nil {
+			return nil, err
+		}
+	}
+	var implicit func(fields []config.Field) error
+	implicit = func(fields []config.Field) error {
+		for _, f := range fields {
+			if err := implicit(f.Fields); err != nil {
+				return err
+			}
+			if f.From == "" {
+				continue
+			}
+			name, _, _ := strings.Cut(f.From, ".")
+			if findEntity(entities, name) >= 0 || child && name == parentName {
+				continue
+			}
+			if _, ok := entity.Attributes[name]; !ok {
+				return fmt.Errorf("field '%s': unknown entity '%s'", f.Name, name)
+			}
+			settings := map[string]interface{}{"locale": cfg.Locale}
+			if err := add(config.Entity{Name: name, Type: name, Settings: settings}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := implicit(cfg.Fields); err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// findEntity returns the position of the named entity, or -1.
+func findEntity(entities []entityField, name string) int {
+	for i, e := range entities {
+		if e.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// newProjection resolves a field's 'from' reference, returning the projection
+// generator and the position of the entity it reads, or -1 for the parent record.
+func newProjection(sc *scope, f config.Field) (*entity.Projection, int, error) {
+	if f.Generator.Type != "" {
+		return nil, 0, fmt.Errorf("use either 'from' or 'generator', not both")
+	}
+	name, attr, ok := strings.Cut(f.From, ".")
+	if !ok || attr == "" {
+		return nil, 0, fmt.Errorf("'from' must have the form entity.attribute, got '%s'", f.From)
+	}
+	if sc.parent != nil && name == parentName {
+		for _, field := range sc.parent {
+			if field == attr {
+				return entity.NewProjection(name, attr), -1, nil
+			}
+		}
+		return nil, 0, fmt.Errorf("the parent table has no top-level field '%s'", attr)
+	}
+	entities := sc.entities
+	pos := findEntity(entities, name)
+	if !entities[pos].gen.Has(attr) {
+		return nil, 0, fmt.Errorf("entity '%s' has no attribute '%s'", name, attr)
+	}
+	return entity.NewProjection(name, attr), pos, nil
+}
@@ -0,0 +1,201 @@
+package runner
+
+import (
+	"fmt"
+
+	"likha/config"
+	"likha/generator/factory"
+	"likha/generator/types"
+	output_types "likha/output/types"
+	"likha/util"
+)
+
+// childField is a field of an object, or of array items that are objects.
+type childField struct {
+	name  string
+	blank blankRate
+	gen   types.Generator
+}
+
+// objectGenerator builds a map from its child fields. Every child draws from
+// a seed derived from the parent field's random stream, so nested values are
+// as reproducible as top-level ones.
+type objectGenerator struct {
+	fields []childField
+}
+
+// arrayGenerator builds a list of min to max items, each from its own seed.
+type arrayGenerator struct {
+	min, max int
+	item     types.Generator
+}
+
+// newNested creates the generator of an object or array field.
+func newNested(f config.Field, sc *scope) (types.Generator, error) {
+	switch f.Type {
+	case "object":
+		if len(f.Fields) == 0 || f.Generator.Type != "" || f.From != "" || f.Ref != "" {
+			return nil, fmt.Errorf("an object field requires 'fields' and no 'generator', 'from' or 'ref'")
+		}
+		return newObject(f.Fields, sc)
+
+	case "array":
+		sources := 0
+		for _, set := range []bool{len(f.Fields) > 0, f.Generator.Type != "", f.From != "", f.Ref != ""} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return nil, fmt.Errorf("an array field requires exactly one of 'fields', 'generator', 'from' or 'ref' for its items")
+		}
+		if f.MinItems < 0 || f.MaxItems < 1 || f.MinItems > f.MaxItems {
+			return nil, fmt.Errorf("an array field requires 0 <= min_items <= max_items and max_items >= 1")
+		}
+		g := &arrayGenerator{min: f.MinItems, max: f.MaxItems}
+		var err error
+		switch {
+		case len(f.Fields) > 0:
+			g.item, err = newObject(f.Fields, sc)
+		case f.From != "":
+			g.item, _, err = newProjection(sc, f)
+		case f.Ref != "":
+			g.item, err = newRef(sc, f)
+		default:
+			g.item, err = factory.NewGenerator(f.Generator, nil)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return g, nil
+
+	default:
+		return nil, fmt.Errorf("unknown field type '%s' (expected object or array)", f.Type)
+	}
+}
+
+// newObject creates the generator of an object with the given child fields.
+func newObject(fields []config.Field, sc *scope) (*objectGenerator, error) {
+	g := &objectGenerator{}
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if seen[f.Name] {
+			return nil, fmt.Errorf("field '%s' is defined twice", f.Name)
+		}
+		seen[f.Name] = true
+		if f.Unique || f.Seed != nil {
+			return nil, fmt.Errorf("field '%s': unique and seed are only supported on top-level fields", f.Name)
+		}
+		if f.NullRate < 0 || f.EmptyRate < 0 || f.NullRate+f.EmptyRate > 1 {
+			return nil, fmt.Errorf("field '%s': null_rate and empty_rate must be between 0 and 1 and add up to at most 1", f.Name)
+		}
+
+		var gen types.Generator
+		var err error
+		switch {
+		case isNested(f.Type):
+			gen, err = newNested(f, sc)
+		case f.From != "":
+			gen, _, err = newProjection(sc, f)
+		case f.Ref != "":
+			gen, err = newRef(sc, f)
+		default:
+			gen, err = factory.NewGenerator(f.Generator, nil)
+		}
+		if err == nil {
+			gen, err = withType(f, gen)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+		}
+		g.fields = append(g.fields, childField{
+			name:  f.Name,
+			blank: blankRate{null: f.NullRate, empty: f.EmptyRate},
+			gen:   gen,
+		})
+	}
+	return g, nil
+}
+
+// Generate builds the object. While it is built, each child is also set in
+// row, so later siblings can refer to it as #name just like to the fields of
+// the enclosing record; row is restored before returning.
+func (g *objectGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	index := ctx.Index
+	base := ctx.Rand.Int64()
+	obj := make(map[string]interface{}, len(g.fields))
+
+	type shadowed struct {
+		name string
+		prev interface{}
+		had  bool
+	}
+	restore := make([]shadowed, 0, len(g.fields))
+	defer func() {
+		for i := len(restore) - 1; i >= 0; i-- {
+			s := restore[i]
+			if s.had {
+				row[s.name] = s.prev
+			} else {
+				delete(row, s.name)
+			}
+		}
+	}()
+
+	for _, c := range g.fields {
+		seed := util.DeriveSeed(base, c.name)
+		val, blank := c.blank.pick(seed, index)
+		if !blank {
+			ctx.Reset(index, seed)
+			var err error
+			val, err = c.gen.Generate(ctx, row)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %w", c.name, err)
+			}
+		}
+		obj[c.name] = val
+		prev, had := row[c.name]
+		restore = append(restore, shadowed{name: c.name, prev: prev, had: had})
+		row[c.name] = val
+	}
+	return obj, nil
+}
+
+// Generate builds the array.
+func (g *arrayGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	index := ctx.Index
+	n := g.min + ctx.Rand.IntN(g.max-g.min+1)
+	base := ctx.Rand.Uint64()
+	items := make([]interface{}, n)
+	for i := range items {
+		ctx.Reset(index, int64(util.Mix64(base^util.Mix64(uint64(i)))))
+		val, err := g.item.Generate(ctx, row)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		items[i] = val
+	}
+	return items, nil
+}
+
+// columnsOf describes fields, including nested ones, for the output writers.
+func columnsOf(fields []config.Field) []output_types.Column {
+	cols := make([]output_types.Column, len(fields))
+	for i, f := range fields {
+		c := output_types.Column{Name: f.Name}
+		switch f.Type {
+		case "object":
+			c.Object = true
+			c.Children = columnsOf(f.Fields)
+		case "array":
+			c.Array = true
+			c.MaxItems = f.MaxItems
+			if len(f.Fields) > 0 {
+				c.Object = true
+				c.Children = columnsOf(f.Fields)
+			}
+		}
+		cols[i] = c
+	}
+	return cols
+}
@@ -0,0 +1,51 @@
+package runner
+
+import "context"
+
+// reorderBuffer restores index order for results that arrive out of order
+// from the worker pool. It is bounded: the dispatcher must Acquire a slot
+// before handing out a job, and the slot is only released once that row has
+// been written, so at most `size` rows are ever held in memory.
+type reorderBuffer struct {
+	slots   chan struct{}
+	pending map[int64]Result
+	next    int64
+}
+
+// newReorderBuffer creates a buffer holding up to size rows, expecting the
+// first result to carry the index start.
+func newReorderBuffer(size int, start int64) *reorderBuffer {
+	return &reorderBuffer{
+		slots:   make(chan struct{}, size),
+		pending: make(map[int64]Result, size),
+		next:    start,
+	}
+}
+
+// Acquire blocks until the buffer has room for one more in-flight row.
+// It returns false if ctx is cancelled first.
+func (b *reorderBuffer) Acquire(ctx context.Context) bool {
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Push adds a result and returns the results that are now ready to be
+// written, in index order. Each returned result releases its slot.
+func (b *reorderBuffer) Push(res Result) []Result {
+	b.pending[res.Index] = res
+	var ready []Result
+	for {
+		next, ok := b.pending[b.next]
+		if !ok {
+			return ready
+		}
+		delete(b.pending, b.next)
+		b.next++
+		<-b.slots
+		ready = append(ready, next)
+	}
+}
@@ -1,18 +1,29 @@
 package runner
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"likha/config"
 
 	"likha/generator/factory"
+	"likha/generator/linear"
 	"likha/generator/types"
 	"likha/output"
 	output_types "likha/output/types"
 	"likha/progress"
+	"likha/util"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -21,38 +32,165 @@ import (
 type Runner struct {
 	config       *config.Config
 	count        int64
+	seed         int64
+	opts         Options
 	generators   map[string]types.Generator
 	fieldOrder   []string
+	columns      []output_types.Column // Output layout, including nested fields
+	fieldSeeds   []int64               // Seed of each field, aligned with fieldOrder
+	entities     []entityField
+	keys         []*keyColumn  // Fields referenced by other tables, collected as rows are written
+	children     []*childTable // Child tables, generated along with each record
+	child        bool          // Generated by the parent table's runner; the record count is not known up front
+	blankRates   []blankRate
+	uniqueFields []*uniqueField
 	writer       output_types.Writer
-	prog         *tea.Program
+	buf          *bufio.Writer             // Buffers writes to the output destination
+	file         *os.File                  // Output file; nil when streaming to stdout
+	prog         *tea.Program              // Nil in headless mode
 	progressChan chan progress.ProgressMsg // Channel to send progress updates to the Bubble Tea model
+	printer      *progress.Printer         // Plain-text or JSON progress, used in headless mode
 }
 
+// ErrInterrupted is returned by Run when generation stops before all records are written.
+var ErrInterrupted = errors.New("generation interrupted")
+
+// Options tunes how a Runner executes.
+type Options struct {
+	// Workers is the number of generator goroutines. Zero means runtime.NumCPU().
+	Workers int
+	// Start is the index of the first record to generate. Combined with a
+	// seed, it allows any range of records to be regenerated on its own.
+	Start int64
+	// Unordered writes rows as soon as workers finish them instead of
+	// strictly by index, trading stable output for maximum throughput.
+	Unordered bool
+	// ReorderWindow caps how many rows may be in flight while waiting for an
+	// earlier row in ordered mode. Zero means defaultReorderWindow per worker.
+	ReorderWindow int
+	// Headless runs without the Bubble Tea UI and prints progress lines to stderr instead.
+	Headless bool
+	// ProgressFormat selects the headless progress format: "plain" (default) or "json".
+	ProgressFormat string
+	// ProgressInterval is the delay between headless progress lines. Zero means one second.
+	ProgressInterval time.Duration
+}
+
+// outputBufferSize is the size of the buffer in front of the output destination.
+const outputBufferSize = 64 * 1024
+
+// IsStdout reports whether an output file setting means "write to stdout".
+func IsStdout(file string) bool {
+	return file == "-"
+}
+
+// defaultReorderWindow is the number of in-flight rows allowed per worker in ordered mode.
+const defaultReorderWindow = 256
+
 // Job represents a single row generation task.
 type Job struct {
-	Index int64
+	Index    int64
+	children []childBlock // The record's child records to generate, per child table
 }
 
 // Result holds the data for a generated row.
 type Result struct {
-	Index int64
-	Data  map[string]interface{}
-	Err   error
+	Index    int64
+	Data     map[string]interface{}
+	Err      error
+	children []childBlock
 }
 
 // NewRunner creates and initializes a new Runner.
-func NewRunner(cfg *config.Config, count int64) (*Runner, error) {
+func NewRunner(cfg *config.Config, count int64, opts Options) (*Runner, error) {
+	if len(cfg.Tables) > 0 {
+		return nil, fmt.Errorf("the config defines tables; use NewSchema")
+	}
+	return newRunner(cfg, count, opts, &scope{})
+}
+
+// checkDependsOn reports a linear generator input that is not one of the
+// earlier fields, so a misspelled or misplaced name fails before any record
+// is generated.
+func checkDependsOn(g types.Generator, earlier []string) error {
+	l, ok := g.(*linear.LinearGenerator)
+	if !ok {
+		return nil
+	}
+	for _, name := range l.DependsOn() {
+		if !slices.Contains(earlier, name) {
+			return fmt.Errorf("linear 'depends_on' field '%s' not found; it must come before this field", name)
+		}
+	}
+	return nil
+}
+
+// newRunner creates a Runner. sc holds the keys of other tables and, for a
+// child table, the fields of its parent; its entities are set here.
+func newRunner(cfg *config.Config, count int64, opts Options, sc *scope) (*Runner, error) {
 	gens := make(map[string]types.Generator)
 	fieldOrder := make([]string, len(cfg.Fields))
+	fieldSeeds := make([]int64, len(cfg.Fields))
+	blankRates := make([]blankRate, len(cfg.Fields))
+
+	// Without an explicit seed the output is random, as before.
+	seed := time.Now().UnixNano()
+	if cfg.Seed != nil {
+		seed = *cfg.Seed
+	}
+
+	entities, err := newEntities(cfg, seed, sc.parent != nil)
+	if err != nil {
+		return nil, err
+	}
+	sc.entities = entities
 
 	// First pass: create all non-foreignkey generators to ensure dependencies are available.
+	var uniqueFields []*uniqueField
 	for i, f := range cfg.Fields {
 		fieldOrder[i] = f.Name
-		if f.Generator.Type != "foreignkey" {
+		fieldSeeds[i] = fieldSeed(seed, f)
+		if f.NullRate < 0 || f.EmptyRate < 0 || f.NullRate+f.EmptyRate > 1 {
+			return nil, fmt.Errorf("field '%s': null_rate and empty_rate must be between 0 and 1 and add up to at most 1", f.Name)
+		}
+		blankRates[i] = blankRate{null: f.NullRate, empty: f.EmptyRate}
+		if f.Unique {
+			uf, err := newUniqueField(i, f, count)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+			}
+			uniqueFields = append(uniqueFields, uf)
+		}
+		if isNested(f.Type) {
+			g, err := newNested(f, sc)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+			}
+			gens[f.Name] = g
+		} else if f.From != "" {
+			p, pos, err := newProjection(sc, f)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+			}
+			gens[f.Name] = p
+			if f.Unique {
+				// A duplicate is resolved by regenerating the whole entity.
+				uniqueFields[len(uniqueFields)-1].slot = len(cfg.Fields) + pos
+			}
+		} else if f.Ref != "" {
+			g, err := newRef(sc, f)
+			if err != nil {
+				return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+			}
+			gens[f.Name] = g
+		} else if f.Generator.Type != "foreignkey" {
 			g, err := factory.NewGenerator(f.Generator, gens)
 			if err != nil {
 				return nil, fmt.Errorf("error creating generator for field '%s': %w", f.Name, err)
 			}
+			if err := checkDependsOn(g, fieldOrder[:i]); err != nil {
+				return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+			}
 			gens[f.Name] = g
 		}
 	}
@@ -66,166 +204,461 @@ func NewRunner(cfg *config.Config, count int64) (*Runner, error) {
 			gens[f.Name] = g
 		}
 	}
+	// Third pass: convert values to the field's type, if it names one.
+	for _, f := range cfg.Fields {
+		g, err := withType(f, gens[f.Name])
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %w", f.Name, err)
+		}
+		gens[f.Name] = g
+	}
 
-	// Create the output file.
-	file, err := os.Create(cfg.Output.File)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create output file '%s': %w", cfg.Output.File, err)
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.ReorderWindow <= 0 {
+		opts.ReorderWindow = opts.Workers * defaultReorderWindow
+	}
+	// Unique fields are checked as rows are written, and retried rows only
+	// come out the same in every run if they are checked in index order.
+	if len(uniqueFields) > 0 {
+		opts.Unordered = false
 	}
 
+	// Create the output file, or stream to stdout when the file is "-".
+	var file *os.File
+	dest := io.Writer(os.Stdout)
+	if !IsStdout(cfg.Output.File) {
+		file, err = os.Create(cfg.Output.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output file '%s': %w", cfg.Output.File, err)
+		}
+		dest = file
+	}
+	buf := bufio.NewWriterSize(dest, outputBufferSize)
+
 	// Create the appropriate writer.
-	writer, err := output.NewWriter(&cfg.Output, file)
+	writer, err := output.NewWriter(&cfg.Output, buf)
 	if err != nil {
-		file.Close() // Clean up the file if writer creation fails.
+		if file != nil {
+			file.Close() // Clean up the file if writer creation fails.
+		}
 		return nil, fmt.Errorf("failed to create writer: %w", err)
 	}
 
-	// Initialize the progress bar model and get its update channel.
-	progressModel, progressChan := progress.NewModel(count)
-	p := tea.NewProgram(progressModel)
-
-	return &Runner{
+	r := &Runner{
 		config:       cfg,
 		count:        count,
+		seed:         seed,
+		opts:         opts,
 		generators:   gens,
 		fieldOrder:   fieldOrder,
+		columns:      columnsOf(cfg.Fields),
+		fieldSeeds:   fieldSeeds,
+		entities:     entities,
+		blankRates:   blankRates,
+		uniqueFields: uniqueFields,
 		writer:       writer,
-		prog:         p,
-		progressChan: progressChan, // Store the channel to send updates
-	}, nil
+		buf:          buf,
+		file:         file,
+	}
+
+	if opts.Headless {
+		r.printer, err = progress.NewPrinter(os.Stderr, opts.ProgressFormat, count)
+		if err != nil {
+			r.closeWriter(nil)
+			return nil, err
+		}
+	} else {
+		// Initialize the progress bar model and get its update channel.
+		progressModel, progressChan := progress.NewModel(count)
+		r.prog = tea.NewProgram(progressModel)
+		r.progressChan = progressChan // Store the channel to send updates
+	}
+
+	return r, nil
 }
 
-// Run starts the generation process using a worker pool and shows a progress bar.
-func (r *Runner) Run() error {
-	// Ensure the writer is closed and the file handle is released on exit.
-	if c, ok := r.writer.(interface{ Close() error }); ok {
-		defer c.Close()
+// fieldSeed returns the seed for a field: its own seed if configured,
+// otherwise one derived from the global seed and the field name.
+func fieldSeed(seed int64, f config.Field) int64 {
+	if f.Seed != nil {
+		return *f.Seed
+	}
+	return util.DeriveSeed(seed, f.Name)
+}
+
+// blankRate holds a field's null and empty-string rates.
+type blankRate struct {
+	null  float64
+	empty float64
+}
+
+// pick decides whether the field is null or empty for this record, returning
+// the replacement value and true if so. The decision comes from its own hash
+// of seed and index rather than the field's random stream, so enabling a rate
+// does not change the values of the remaining records.
+func (b blankRate) pick(seed int64, index int64) (interface{}, bool) {
+	if b.null == 0 && b.empty == 0 {
+		return nil, false
+	}
+	u := float64(util.Mix64(uint64(seed)^util.Mix64(uint64(index))^blankSalt)>>11) / (1 << 53)
+	switch {
+	case u < b.null:
+		return nil, true
+	case u < b.null+b.empty:
+		return "", true
 	}
+	return nil, false
+}
 
+// blankSalt separates the null/empty decision from other uses of the field seed.
+const blankSalt = 0x6e756c6c72617465
+
+// Run starts the generation process using a worker pool and reports progress,
+// either with a progress bar or, in headless mode, with periodic lines on stderr.
+func (r *Runner) Run() error {
 	// Write the header row for formats that support it (e.g., CSV).
-	if err := r.writer.WriteHeader(r.fieldOrder); err != nil {
-		return fmt.Errorf("failed to write header: %w", err)
+	if err := r.writer.WriteHeader(r.columns); err != nil {
+		return r.closeWriter(fmt.Errorf("failed to write header: %w", err))
 	}
+	for _, c := range r.children {
+		if err := c.runner.writer.WriteHeader(c.runner.columns); err != nil {
+			return r.closeWriter(fmt.Errorf("table '%s': failed to write header: %w", c.name, err))
+		}
+	}
+
+	if r.opts.Headless {
+		return r.runHeadless()
+	}
+	return r.closeWriter(r.runTUI())
+}
+
+// closeWriter closes the writer, and those of the child tables, removes the
+// files of disk-mode unique sets and returns err, or the close error if err
+// is nil. Stdout is never closed, only flushed.
+func (r *Runner) closeWriter(err error) error {
+	for _, c := range r.children {
+		err = c.runner.closeWriter(err)
+	}
+	if uerr := r.closeUnique(); uerr != nil && err == nil {
+		err = uerr
+	}
+	if cerr := r.writer.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("failed to close writer: %w", cerr)
+	}
+	if ferr := r.buf.Flush(); ferr != nil && err == nil {
+		err = fmt.Errorf("failed to flush output: %w", ferr)
+	}
+	if r.file != nil {
+		if cerr := r.file.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close output file: %w", cerr)
+		}
+	}
+	return err
+}
+
+// runTUI runs the pipeline in the background while Bubble Tea renders the progress bar.
+func (r *Runner) runTUI() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Run the pipeline in the background and forward its progress to the progress bar.
+	genErr := make(chan error, 1)
+	go func() {
+		defer close(r.progressChan) // Signal the progress model that no more updates are coming
+		err := r.generate(ctx, func(processed int64) {
+			r.sendProgress(ctx, progress.ProgressMsg{
+				Current: processed,
+				Total:   r.count,
+				Done:    processed == r.count,
+			})
+		})
+		if err != nil && ctx.Err() == nil {
+			r.sendProgress(ctx, progress.ProgressMsg{Total: r.count, Error: err})
+		}
+		genErr <- err
+	}()
+
+	// Start the Bubble Tea progress bar in the main thread (this blocks until quit)
+	// The program will quit when 100% progress is reached or an error is sent.
+	_, err := r.prog.Run()
+	// Stop generation if the user quit early, then wait for the pipeline to wind down
+	// before the writer is closed.
+	cancel()
+	if gerr := <-genErr; gerr != nil {
+		if gerr == context.Canceled {
+			return ErrInterrupted
+		}
+		return gerr
+	}
+	return err
+}
+
+// runHeadless runs the pipeline in the foreground, printing a progress line
+// every ProgressInterval and a summary once the output is closed.
+// SIGINT and SIGTERM stop generation cleanly.
+func (r *Runner) runHeadless() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	interval := r.opts.ProgressInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	var processed atomic.Int64
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.printer.Progress(processed.Load())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err := r.generate(ctx, func(n int64) { processed.Store(n) })
+	close(done)
+	if err == context.Canceled {
+		err = ErrInterrupted
+	}
+	err = r.closeWriter(err)
+	r.printer.Summary(processed.Load(), r.destination(), err)
+	if err == nil {
+		for _, c := range r.children {
+			c.runner.printer.Summary(c.written, c.runner.destination(), nil)
+		}
+	}
+	return err
+}
+
+// destination names where the output goes, for progress summaries.
+func (r *Runner) destination() string {
+	if r.file == nil {
+		return "stdout"
+	}
+	return r.config.Output.File
+}
+
+// sendProgress delivers a progress update unless the run has been cancelled.
+func (r *Runner) sendProgress(ctx context.Context, msg progress.ProgressMsg) {
+	select {
+	case r.progressChan <- msg:
+	case <-ctx.Done():
+	}
+}
+
+// generate runs the worker pool and writes every result to the writer,
+// in index order unless Options.Unordered is set.
+// report is called with the number of rows written after each row.
+// It returns the first generation or write error, or ctx.Err() if cancelled.
+func (r *Runner) generate(ctx context.Context, report func(processed int64)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	// Set up a worker pool to parallelize generation.
-	numWorkers := runtime.NumCPU()
+	numWorkers := r.opts.Workers
 	jobs := make(chan Job, numWorkers)
 	results := make(chan Result, numWorkers)
 
 	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
 		wg.Add(1)
-		go r.worker(&wg, jobs, results)
+		go r.worker(ctx, &wg, jobs, results)
+	}
+
+	// In ordered mode, rows pass through a bounded reorder buffer. The feeder
+	// must reserve a slot for each job, which stalls it (backpressure) when a
+	// slow row holds up the writer.
+	var reorder *reorderBuffer
+	if !r.opts.Unordered {
+		reorder = newReorderBuffer(r.opts.ReorderWindow, r.opts.Start)
 	}
 
-	// Start the actual work in a goroutine
+	// Feed jobs to the workers. Child record counts are decided here, in
+	// index order, so each child table's records are numbered consecutively.
+	childIndex := r.firstChildIndexes(types.NewContext())
 	go func() {
-		defer func() {
-			// Wait for all workers to finish
-			wg.Wait()
-			close(results) // Close the results channel after all workers are done
-			// Close the progress channel to signal the progress model that no more updates are coming
-			close(r.progressChan)
-		}()
-
-		// Goroutine to feed jobs to the workers.
-		go func() {
-			for i := int64(0); i < r.count; i++ {
-				jobs <- Job{Index: i}
+		defer close(jobs)
+		feedCtx := types.NewContext()
+		for i := int64(0); i < r.count; i++ {
+			if reorder != nil && !reorder.Acquire(ctx) {
+				return
 			}
-			close(jobs) // Close the jobs channel after all jobs are sent
-		}()
-
-		// Process results from the workers.
-		var processedCount int64
-		var hasError bool // Flag to indicate if an error has occurred
-
-		for processedCount < r.count {
-			if hasError {
-				// If an error has occurred, break from processing further results.
-				// The tea.Program will quit via the ErrorMsg handler.
-				break
+			job := Job{Index: r.opts.Start + i}
+			if len(r.children) > 0 {
+				job.children = make([]childBlock, len(r.children))
+				for j, c := range r.children {
+					n := c.count(feedCtx, job.Index)
+					job.children[j] = childBlock{start: childIndex[j], count: n}
+					childIndex[j] += n
+				}
 			}
-
 			select {
-			case result, ok := <-results:
-				if !ok {
-					// Results channel closed unexpectedly before all records were processed.
-					if !hasError {
-						r.progressChan <- progress.ProgressMsg{
-							Current: processedCount,
-							Total:   r.count,
-							Done:    false,
-							Error:   fmt.Errorf("results channel closed unexpectedly before all records processed (%d/%d)", processedCount, r.count),
-						}
-					}
-					hasError = true
-					break
-				}
-
-				if result.Err != nil {
-					// Send error to progress bar via the channel
-					r.progressChan <- progress.ProgressMsg{
-						Current: processedCount,
-						Total:   r.count,
-						Done:    false,
-						Error:   result.Err,
-					}
-					hasError = true
-					break
-				} else {
-					if err := r.writer.WriteRow(result.Data); err != nil {
-						// Send error to progress bar and stop via the channel
-						r.progressChan <- progress.ProgressMsg{
-							Current: processedCount,
-							Total:   r.count,
-							Done:    false,
-							Error:   fmt.Errorf("failed to write row %d: %w", result.Index, err),
-						}
-						hasError = true
-						break
-					}
-				}
-				processedCount++
-				// Send progress update to the progress bar via the channel
-				r.progressChan <- progress.ProgressMsg{
-					Current: processedCount,
-					Total:   r.count,
-					Done:    processedCount == r.count,
-					Error:   nil,
-				}
+			case jobs <- job:
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
 
-	// Start the Bubble Tea progress bar in the main thread (this blocks until quit)
-	// The program will quit when 100% progress is reached or an ErrorMsg is sent.
-	_, err := r.prog.Run()
-	return err
+	// Close the results channel once all workers are done.
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Process results from the workers. On error, cancel the pipeline and
+	// keep draining so that no worker is left blocked on a send.
+	// Uniqueness is enforced here, on the single writing goroutine, so that
+	// retries happen in a fixed order and need no locking.
+	var processedCount int64
+	var firstErr error
+	uniqueCtx := types.NewContext()
+	write := func(result Result) {
+		if firstErr != nil {
+			return
+		}
+		if len(r.uniqueFields) > 0 {
+			data, retried, err := r.ensureUnique(uniqueCtx, result.Index, result.Data)
+			if err == nil && retried && len(r.children) > 0 {
+				// The children were generated from the replaced record.
+				err = r.generateChildren(uniqueCtx, result.children, data)
+			}
+			if err != nil {
+				firstErr = err
+				cancel()
+				return
+			}
+			result.Data = data
+		}
+		for _, k := range r.keys {
+			k.set(result.Index-r.opts.Start, result.Data[k.field])
+		}
+		if err := r.writer.WriteRow(result.Data); err != nil {
+			firstErr = fmt.Errorf("failed to write row %d: %w", result.Index, err)
+			cancel()
+			return
+		}
+		if err := r.writeChildren(result.children); err != nil {
+			firstErr = err
+			cancel()
+			return
+		}
+		processedCount++
+		report(processedCount)
+	}
+	for result := range results {
+		if firstErr != nil {
+			continue
+		}
+		if result.Err != nil {
+			firstErr = result.Err
+			cancel()
+			continue
+		}
+		if reorder == nil {
+			write(result)
+			continue
+		}
+		for _, ready := range reorder.Push(result) {
+			write(ready)
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if processedCount < r.count {
+		return ctx.Err()
+	}
+	return nil
 }
 
 // worker is the function run by each goroutine in the pool.
 // It receives jobs, generates data, and sends results back.
-func (r *Runner) worker(wg *sync.WaitGroup, jobs <-chan Job, results chan<- Result) {
+// Each worker owns its generator context, so generators share no mutable state.
+func (r *Runner) worker(ctx context.Context, wg *sync.WaitGroup, jobs <-chan Job, results chan<- Result) {
 	defer wg.Done()
+	genCtx := types.NewContext()
 	for job := range jobs {
-		rowData := make(map[string]interface{})
-		// We must generate fields in the order specified in the config
-		// to ensure dependencies like foreign keys are met.
-		for _, fieldName := range r.fieldOrder {
-			gen, ok := r.generators[fieldName]
-			if !ok {
-				results <- Result{Index: job.Index, Err: fmt.Errorf("internal error: generator for field '%s' not found", fieldName)}
-				goto nextJob // Use goto to break out of nested loops on error
-			}
-			val, err := gen.Generate(rowData)
-			if err != nil {
-				results <- Result{Index: job.Index, Err: fmt.Errorf("field '%s': %w", fieldName, err)}
-				goto nextJob // Use goto to break out of nested loops on error
-			}
-			rowData[fieldName] = val
+		rowData, err := r.generateRecord(genCtx, job.Index)
+		if err == nil && len(job.children) > 0 {
+			err = r.generateChildren(genCtx, job.children, rowData)
+		}
+		select {
+		case results <- Result{Index: job.Index, Data: rowData, Err: err, children: job.children}:
+		case <-ctx.Done():
+			return
 		}
-		results <- Result{Index: job.Index, Data: rowData}
-	nextJob: // Label for goto
 	}
 }
+
+// GenerateRecord generates the record at the given index. The result depends
+// only on the seed and the index, so it matches the record a full run produces,
+// unless a uniqueness retry replaced it during that run. Child records are not
+// included.
+func (r *Runner) GenerateRecord(index int64) (map[string]interface{}, error) {
+	return r.generateRecord(types.NewContext(), index)
+}
+
+// generateRecord generates a single record using the caller-owned context.
+func (r *Runner) generateRecord(ctx *types.Context, index int64) (map[string]interface{}, error) {
+	return r.generateRecordAttempt(ctx, index, nil, nil)
+}
+
+// generateRecordAttempt generates a record, reseeding every field whose
+// entry in attempts is non-zero for that uniqueness retry. Entities follow
+// the fields in attempts. parent is the parent record of a child table's
+// record, and nil otherwise.
+func (r *Runner) generateRecordAttempt(ctx *types.Context, index int64, attempts []int, parent map[string]interface{}) (map[string]interface{}, error) {
+	rowData := make(map[string]interface{})
+	if parent != nil {
+		rowData[parentKey] = parent
+		defer delete(rowData, parentKey)
+	}
+	// Entities come first, so every field can project from them.
+	for j, e := range r.entities {
+		seed := e.seed
+		if attempts != nil && attempts[len(r.fieldOrder)+j] > 0 {
+			seed = retrySeed(seed, attempts[len(r.fieldOrder)+j])
+		}
+		ctx.Reset(index, seed)
+		val, err := e.gen.Generate(ctx, rowData)
+		if err != nil {
+			return nil, fmt.Errorf("entity '%s': %w", e.name, err)
+		}
+		rowData[e.key] = val
+	}
+	// We must generate fields in the order specified in the config
+	// to ensure dependencies like foreign keys are met.
+	for i, fieldName := range r.fieldOrder {
+		gen, ok := r.generators[fieldName]
+		if !ok {
+			return nil, fmt.Errorf("internal error: generator for field '%s' not found", fieldName)
+		}
+		if blank, ok := r.blankRates[i].pick(r.fieldSeeds[i], index); ok {
+			rowData[fieldName] = blank
+			continue
+		}
+		seed := r.fieldSeeds[i]
+		if attempts != nil && attempts[i] > 0 {
+			seed = retrySeed(seed, attempts[i])
+		}
+		ctx.Reset(index, seed)
+		val, err := gen.Generate(ctx, rowData)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %w", fieldName, err)
+		}
+		rowData[fieldName] = val
+	}
+	for _, e := range r.entities {
+		delete(rowData, e.key)
+	}
+	return rowData, nil
+}
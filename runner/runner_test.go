@@ -0,0 +1,1023 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"likha/config"
+	"likha/fake"
+)
+
+// allGeneratorsConfig exercises every generator type so that `go test -race`
+// covers them all under the real worker pool.
+const allGeneratorsConfig = `
+seed: 1234
+entities:
+  - name: "profile"
+    type: "correlated"
+    settings:
+      variables:
+        - {name: "age", mean: 40, stddev: 12, min: 18}
+        - {name: "income", distribution: "lognormal", mean: 50000, stddev: 20000}
+      correlation: [[1, 0.6], [0.6, 1]]
+fields:
+  - name: "constant"
+    generator:
+      type: "simple"
+      settings:
+        value: "fixed"
+  - name: "status"
+    generator:
+      type: "list"
+      settings:
+        values: ["active", "inactive", "pending"]
+  - name: "epoch"
+    generator:
+      type: "builtin"
+      settings:
+        function: "random_epoch"
+        start: 1600000000
+        end: 1700000000
+  - name: "date"
+    generator:
+      type: "builtin"
+      settings:
+        function: "random_isodate"
+        start_date: "2022-01-01T00:00:00Z"
+        end_date: "2023-01-01T00:00:00Z"
+  - name: "code"
+    generator:
+      type: "builtin"
+      settings:
+        function: "random_string"
+        length: 8
+  - name: "amount"
+    generator:
+      type: "builtin"
+      settings:
+        function: "random_int"
+        min: 1
+        max: 1000
+  - name: "price"
+    generator:
+      type: "builtin"
+      settings:
+        function: "random_decimal"
+        min: 1
+        max: 100
+  - name: "email"
+    generator:
+      type: "expression"
+      settings:
+        expression: "#status-$random_int(10,99)-$random_string(4)@example.com"
+  - name: "total"
+    generator:
+      type: "expression"
+      settings:
+        expression: "${#amount * #price > 5000 ? 'large' : $random_int(1, 3) * #amount}"
+  - name: "price_cents"
+    type: "int"
+    generator:
+      type: "expression"
+      settings:
+        expression: "${#price * 100}"
+  - name: "day"
+    type: "date"
+    generator:
+      type: "expression"
+      settings:
+        expression: "#date"
+  - name: "slug"
+    generator:
+      type: "expression"
+      settings:
+        expression: "$slug(#status)-$pad(#amount, 6, '0')-$format_date($date_add(#date, 1, 'month'), '%Y%m%d')"
+  - name: "age"
+    type: "int"
+    from: "profile.age"
+  - name: "profile_income"
+    from: "profile.income"
+  - name: "spend"
+    type: "decimal"
+    generator:
+      type: "linear"
+      settings:
+        depends_on: ["profile_income", "age"]
+        coefficients: [0.05, -10]
+        intercept: 200
+        noise: 0.1
+        noise_model: "multiplicative"
+        min: 0
+  - name: "seen_at"
+    generator:
+      type: "timeseries"
+      settings:
+        interval: "5m"
+        jitter: 0.5
+        hourly: "business"
+        bursts:
+          every: "2h"
+          duration: "10m"
+  - name: "reading"
+    generator:
+      type: "series"
+      settings:
+        base: 20
+        step: 0.1
+        amplitude: 5
+        period: "24h"
+        time_field: "seen_at"
+        noise: 0.2
+  - name: "bio"
+    generator:
+      type: "text"
+      settings:
+        max_sentences: 4
+        max_length: 200
+        hashtags: {max: 2}
+        mentions: {template: "#status"}
+        urls: {template: "https://example.com/#order_id"}
+  - name: "device"
+    generator:
+      type: "foreignkey"
+      source_field: "status"
+      map:
+        "active":
+          type: "list"
+          settings:
+            values: ["mobile", "desktop"]
+        "inactive":
+          type: "simple"
+          settings:
+            value: "none"
+  - name: "order_id"
+    generator:
+      type: "sequence"
+      settings:
+        start: 100
+        step: 5
+        format: "ORD-%08d"
+  - name: "external"
+    generator:
+      type: "custom"
+      settings:
+        command: "echo external"
+  - name: "ticket"
+    generator:
+      type: "regex"
+      settings:
+        pattern: '[A-Z]{3}-\d{4}-(foo|bar)+'
+  - name: "city"
+    from: "address.city"
+  - name: "state"
+    from: "address.state"
+  - name: "customer"
+    type: "object"
+    fields:
+      - name: "tier"
+        generator:
+          type: "list"
+          settings:
+            values: ["gold", "silver"]
+      - name: "label"
+        generator:
+          type: "expression"
+          settings:
+            expression: "#tier-#status"
+  - name: "items"
+    type: "array"
+    min_items: 1
+    max_items: 3
+    fields:
+      - name: "sku"
+        generator:
+          type: "regex"
+          settings:
+            pattern: 'SKU-\d{4}'
+output:
+  type: "csv"
+`
+
+// newTestRunner loads the given YAML config and builds a Runner writing CSV to a temp file.
+func newTestRunner(t *testing.T, yamlConfig string, count int64, opts Options) (*Runner, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(yamlConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Output.File = filepath.Join(dir, "out.csv")
+
+	r, err := NewRunner(cfg, count, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, cfg.Output.File
+}
+
+// runToLines runs the pipeline without the progress UI and returns the data rows.
+func runToLines(t *testing.T, r *Runner, outPath string) []string {
+	t.Helper()
+	if err := r.writer.WriteHeader(r.columns); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.generate(context.Background(), func(int64) {}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	return lines[1:]
+}
+
+func TestAllGeneratorsConcurrent(t *testing.T) {
+	const count = 500
+
+	r, out := newTestRunner(t, allGeneratorsConfig, count, Options{Workers: 1})
+	serial := runToLines(t, r, out)
+	if len(serial) != count {
+		t.Fatalf("expected %d rows, got %d", count, len(serial))
+	}
+
+	// A tiny reorder window forces the feeder to block on backpressure.
+	r, out = newTestRunner(t, allGeneratorsConfig, count, Options{Workers: 32, ReorderWindow: 4})
+	parallel := runToLines(t, r, out)
+	if strings.Join(serial, "\n") != strings.Join(parallel, "\n") {
+		t.Fatal("output differs between 1 and 32 workers")
+	}
+
+	r, out = newTestRunner(t, allGeneratorsConfig, count, Options{Workers: 32, Unordered: true})
+	unordered := runToLines(t, r, out)
+	sort.Strings(serial)
+	sort.Strings(unordered)
+	if strings.Join(serial, "\n") != strings.Join(unordered, "\n") {
+		t.Fatal("unordered output contains different rows")
+	}
+}
+
+func TestGenerateRecordAtStart(t *testing.T) {
+	r, out := newTestRunner(t, allGeneratorsConfig, 20, Options{Workers: 4})
+	full := runToLines(t, r, out)
+
+	r, out = newTestRunner(t, allGeneratorsConfig, 5, Options{Workers: 4, Start: 10})
+	part := runToLines(t, r, out)
+	if strings.Join(full[10:15], "\n") != strings.Join(part, "\n") {
+		t.Fatal("records generated from --start differ from the full run")
+	}
+}
+
+func TestGenerateRecordMatchesRun(t *testing.T) {
+	const count = 200
+
+	r, out := newTestRunner(t, allGeneratorsConfig, count, Options{Workers: 8})
+	run := runToLines(t, r, out)
+
+	// Write GenerateRecord's records through the same writer setup.
+	single, singleOut := newTestRunner(t, allGeneratorsConfig, count, Options{Workers: 1})
+	if err := single.writer.WriteHeader(single.columns); err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < count; i++ {
+		rec, err := single.GenerateRecord(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := single.writer.WriteRow(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := single.writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(singleOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := strings.Split(strings.TrimSpace(string(data)), "\n")[1:]
+
+	if len(records) != len(run) {
+		t.Fatalf("GenerateRecord gave %d rows, the run gave %d", len(records), len(run))
+	}
+	for i := range run {
+		if records[i] != run[i] {
+			t.Fatalf("record %d differs from the run:\n%s\n%s", i, records[i], run[i])
+		}
+	}
+}
+
+func TestTimeseriesIncreases(t *testing.T) {
+	r, _ := newTestRunner(t, allGeneratorsConfig, 1, Options{Workers: 1})
+	var prev time.Time
+	for i := int64(0); i < 2000; i++ {
+		rec, err := r.GenerateRecord(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := rec["seen_at"].(time.Time)
+		if ts.Before(prev) {
+			t.Fatalf("record %d at %v is before the previous record at %v", i, ts, prev)
+		}
+		prev = ts
+	}
+}
+
+func TestEntitiesAreConsistent(t *testing.T) {
+	for _, code := range []string{"en_US", "de_DE", "ja_JP"} {
+		cfg := fmt.Sprintf(`
+seed: 21
+entities:
+  - {name: "buyer", type: "person", settings: {locale: %[1]q}}
+  - {name: "home", type: "address", settings: {locale: %[1]q}}
+fields:
+  - {name: "first", from: "buyer.first_name"}
+  - {name: "last", from: "buyer.last_name"}
+  - {name: "email", from: "buyer.email"}
+  - {name: "username", from: "buyer.username"}
+  - {name: "city", from: "home.city"}
+  - {name: "state", from: "home.state"}
+  - {name: "state_code", from: "home.state_code"}
+  - {name: "postal_code", from: "home.postal_code"}
+  - {name: "latitude", from: "home.latitude"}
+  - {name: "longitude", from: "home.longitude"}
+output:
+  type: "csv"
+`, code)
+		l, err := fake.Get(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		latin := make(map[string]string)
+		for _, names := range [][]fake.Name{l.MaleNames, l.FemaleNames, l.LastNames} {
+			for _, n := range names {
+				latin[n.Native] = strings.ReplaceAll(strings.ToLower(n.Latin), " ", "")
+			}
+		}
+		domains := strings.Join(l.EmailDomains, "|")
+
+		r, _ := newTestRunner(t, cfg, 1, Options{Workers: 1})
+		for i := int64(0); i < 300; i++ {
+			rec, err := r.GenerateRecord(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var city *fake.City
+			for j := range l.Cities {
+				if c := &l.Cities[j]; c.Name == rec["city"] && c.State == rec["state"] && c.StateCode == rec["state_code"] {
+					city = c
+				}
+			}
+			if city == nil {
+				t.Fatalf("%s: %v, %v is not a city of the dataset", code, rec["city"], rec["state"])
+			}
+			postal := "^" + strings.NewReplacer("#", `\d`, "%", "[1-9]").Replace(regexp.QuoteMeta(city.Postal)) + "$"
+			if !regexp.MustCompile(postal).MatchString(rec["postal_code"].(string)) {
+				t.Fatalf("%s: postal code %v does not belong to %s", code, rec["postal_code"], city.Name)
+			}
+			if math.Abs(rec["latitude"].(float64)-city.Lat) > 0.05+1e-6 || math.Abs(rec["longitude"].(float64)-city.Lon) > 0.05+1e-6 {
+				t.Fatalf("%s: %v, %v is too far from %s", code, rec["latitude"], rec["longitude"], city.Name)
+			}
+
+			first, last := regexp.QuoteMeta(latin[rec["first"].(string)]), regexp.QuoteMeta(latin[rec["last"].(string)])
+			f := first[:1]
+			email := fmt.Sprintf(`^(%[1]s\.%[2]s|%[1]s%[2]s\d\d|%[3]s%[2]s|%[1]s_%[2]s|%[2]s\.%[1]s[1-9]\d|%[1]s[1-9]\d\d)@(%[4]s)$`,
+				first, last, f, strings.ReplaceAll(domains, ".", `\.`))
+			if !regexp.MustCompile(email).MatchString(rec["email"].(string)) {
+				t.Fatalf("%s: email %v does not match %v %v", code, rec["email"], rec["first"], rec["last"])
+			}
+			username := fmt.Sprintf(`^(%[1]s\.%[2]s|%[3]s%[2]s\d\d|%[1]s_%[2]s[1-9]\d|%[1]s%[2]s)$`, first, last, f)
+			if !regexp.MustCompile(username).MatchString(rec["username"].(string)) {
+				t.Fatalf("%s: username %v does not match %v %v", code, rec["username"], rec["first"], rec["last"])
+			}
+		}
+	}
+}
+
+func TestWeightedFrequencies(t *testing.T) {
+	const weighted = `
+seed: 17
+fields:
+  - name: "status"
+    generator:
+      type: "list"
+      settings:
+        values: ["a", "b", "c", "d"]
+        weights: [6, 3, 1, 0]
+  - name: "device"
+    generator:
+      type: "foreignkey"
+      source_field: "status"
+      map:
+        "a":
+          - {type: "simple", weight: 3, settings: {value: "mobile"}}
+          - {type: "simple", settings: {value: "desktop"}}
+          - {type: "simple", weight: 0, settings: {value: "never"}}
+output:
+  type: "csv"
+`
+	r, _ := newTestRunner(t, weighted, 1, Options{Workers: 1})
+	const n = 20000
+	counts := make(map[interface{}]float64)
+	for i := int64(0); i < n; i++ {
+		rec, err := r.GenerateRecord(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[rec["status"]]++
+		if rec["status"] == "a" {
+			counts[rec["device"]]++
+		}
+	}
+	for _, want := range []struct {
+		value interface{}
+		share float64
+		of    float64
+	}{
+		{"a", 0.6, n}, {"b", 0.3, n}, {"c", 0.1, n}, {"d", 0, n},
+		{"mobile", 0.75, counts["a"]}, {"desktop", 0.25, counts["a"]}, {"never", 0, counts["a"]},
+	} {
+		if got := counts[want.value] / want.of; math.Abs(got-want.share) > 0.02 {
+			t.Errorf("%v is picked %.3f of the time, expected %.2f", want.value, got, want.share)
+		}
+	}
+
+	negative := strings.Replace(weighted, "weight: 0,", "weight: -1,", 1)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(negative), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Output.File = filepath.Join(t.TempDir(), "out.csv")
+	if _, err := NewRunner(cfg, 1, Options{}); err == nil || !strings.Contains(err.Error(), "non-negative") {
+		t.Fatalf("expected a negative weight to be rejected, got %v", err)
+	}
+}
+
+func TestCorrelatedFields(t *testing.T) {
+	const correlated = `
+seed: 9
+entities:
+  - name: "profile"
+    type: "correlated"
+    settings:
+      variables:
+        - {name: "age", mean: 40, stddev: 12, min: 18}
+        - {name: "income", distribution: "lognormal", mean: 50000, stddev: 20000}
+      correlation: [[1, 0.6], [0.6, 1]]
+fields:
+  - name: "age"
+    type: "int"
+    from: "profile.age"
+  - name: "profile_income"
+    from: "profile.income"
+output:
+  type: "csv"
+`
+	r, _ := newTestRunner(t, correlated, 1, Options{Workers: 1})
+	const n = 5000
+	var sx, sy, sxx, syy, sxy float64
+	for i := int64(0); i < n; i++ {
+		rec, err := r.GenerateRecord(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, y := float64(rec["age"].(int64)), rec["profile_income"].(float64)
+		sx, sy, sxx, syy, sxy = sx+x, sy+y, sxx+x*x, syy+y*y, sxy+x*y
+	}
+	corr := (n*sxy - sx*sy) / math.Sqrt((n*sxx-sx*sx)*(n*syy-sy*sy))
+	if corr < 0.45 || corr > 0.75 {
+		t.Fatalf("age and income correlate at %.2f, expected about 0.6", corr)
+	}
+}
+
+func TestLinearDependsOnChecked(t *testing.T) {
+	const linear = `
+output:
+  type: "csv"
+fields:
+  - name: "base"
+    generator:
+      type: "sequence"
+  - name: "total"
+    generator:
+      type: "linear"
+      settings:
+        depends_on: [%s]
+  - name: "later"
+    generator:
+      type: "sequence"
+`
+	tests := []struct {
+		dependsOn string
+		missing   string
+	}{
+		{`"base"`, ""},
+		{`"bsae"`, "bsae"},
+		{`"base", "later"`, "later"},
+		{`"total"`, "total"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(linear, tt.dependsOn)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Output.File = filepath.Join(t.TempDir(), "out.csv")
+		_, err = NewRunner(cfg, 1, Options{})
+		if tt.missing == "" {
+			if err != nil {
+				t.Errorf("depends_on %s: %v", tt.dependsOn, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), "'"+tt.missing+"' not found") {
+			t.Errorf("depends_on %s: expected '%s' to be reported, got %v", tt.dependsOn, tt.missing, err)
+		}
+	}
+}
+
+func TestTextMarkov(t *testing.T) {
+	corpus := filepath.Join(t.TempDir(), "corpus.txt")
+	const source = "The cat sat on the mat. The dog sat on the rug! A cat and a dog met on the mat."
+	if err := os.WriteFile(corpus, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := fmt.Sprintf(`
+fields:
+  - name: "post"
+    generator:
+      type: "text"
+      settings:
+        model: "markov"
+        corpus: %q
+        order: 1
+        max_words: 8
+        max_length: 60
+        hashtags: {min: 1, max: 1, values: ["pets"]}
+output:
+  type: "csv"
+`, corpus)
+	r, _ := newTestRunner(t, cfg, 1, Options{Workers: 1})
+	known := make(map[string]bool)
+	for _, w := range strings.Fields(source) {
+		known[strings.TrimRight(w, ".!")] = true
+	}
+	for i := int64(0); i < 200; i++ {
+		rec, err := r.GenerateRecord(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		post := rec["post"].(string)
+		if utf8.RuneCountInString(post) > 60 || !strings.HasSuffix(post, " #pets") {
+			t.Fatalf("record %d: %q is too long or lacks its hashtag", i, post)
+		}
+		for _, w := range strings.Fields(strings.TrimSuffix(post, " #pets")) {
+			if !known[strings.TrimRight(w, ".!")] {
+				t.Fatalf("record %d: %q has word %q, which is not in the corpus", i, post, w)
+			}
+		}
+	}
+}
+
+// uniqueConfig draws one unique field from 1 to max with the given extra settings.
+func uniqueConfig(max int, settings string) string {
+	return fmt.Sprintf(`
+seed: 5
+fields:
+  - name: "code"
+    unique: true
+%s
+    generator:
+      type: "builtin"
+      settings:
+        function: "random_int"
+        min: 1
+        max: %d
+output:
+  type: "csv"
+`, settings, max)
+}
+
+func TestUniqueModes(t *testing.T) {
+	for _, mode := range []string{"exact", "hash", "disk"} {
+		r, out := newTestRunner(t, uniqueConfig(5000, `    unique_mode: "`+mode+`"`), 2000, Options{Workers: 4})
+		lines := runToLines(t, r, out)
+		seen := make(map[string]bool)
+		for _, line := range lines {
+			if seen[line] {
+				t.Fatalf("%s: value %s repeats", mode, line)
+			}
+			seen[line] = true
+		}
+		if len(seen) != 2000 {
+			t.Fatalf("%s: expected 2000 values, got %d", mode, len(seen))
+		}
+	}
+}
+
+func TestUniqueRetries(t *testing.T) {
+	// Filling all 30 values takes many retries for the last few records.
+	r, out := newTestRunner(t, uniqueConfig(30, `    unique_retries: 1000`), 30, Options{Workers: 4})
+	if got := len(runToLines(t, r, out)); got != 30 {
+		t.Fatalf("expected 30 rows, got %d", got)
+	}
+
+	r, _ = newTestRunner(t, uniqueConfig(30, `    unique_retries: 2`), 30, Options{Workers: 4})
+	defer r.writer.Close()
+	err := r.generate(context.Background(), func(int64) {})
+	if err == nil || !strings.Contains(err.Error(), "after 2 retries") {
+		t.Fatalf("expected the retry budget to run out, got %v", err)
+	}
+
+	// A value space smaller than the run is exhausted whatever the budget.
+	r, _ = newTestRunner(t, uniqueConfig(3, ""), 4, Options{Workers: 1})
+	defer r.writer.Close()
+	err = r.generate(context.Background(), func(int64) {})
+	if err == nil || !strings.Contains(err.Error(), "3 unique values so far") {
+		t.Fatalf("expected an exhaustion error, got %v", err)
+	}
+}
+
+func TestUniqueIgnoresUnordered(t *testing.T) {
+	cfg := uniqueConfig(300, "")
+	r, out := newTestRunner(t, cfg, 250, Options{Workers: 1})
+	serial := runToLines(t, r, out)
+	r, out = newTestRunner(t, cfg, 250, Options{Workers: 8, Unordered: true})
+	if parallel := runToLines(t, r, out); strings.Join(serial, "\n") != strings.Join(parallel, "\n") {
+		t.Fatal("retried rows differ with --unordered")
+	}
+}
+
+func TestBloomFilterFalsePositives(t *testing.T) {
+	f := newBloomFilter(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.add(strconv.Itoa(i))
+	}
+	for i := 0; i < 10000; i++ {
+		if !f.contains(strconv.Itoa(i)) {
+			t.Fatalf("key %d was added but is not found", i)
+		}
+	}
+	fp := 0
+	for i := 10000; i < 20000; i++ {
+		if f.contains(strconv.Itoa(i)) {
+			fp++
+		}
+	}
+	if fp > 300 {
+		t.Fatalf("%d false positives in 10000 lookups, expected about 100", fp)
+	}
+}
+
+func TestDiskSetVerifiesPositives(t *testing.T) {
+	// A one-bit filter reports every key once anything is added, so each
+	// lookup goes to the spilled values, across several levels of runs.
+	s := &diskSet{filter: &bloomFilter{bits: make([]uint64, 1), m: 1, k: 1}, values: newSpillSet(7)}
+	const n = 3000
+	for i := 0; i < n; i += 2 {
+		key := strconv.Itoa(i)
+		if dup, err := s.Contains(key); err != nil || dup {
+			t.Fatalf("new key %s reported as a duplicate (%v)", key, err)
+		}
+		if err := s.Add(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.values.runs) < 2 || s.values.runs[0].level == 0 {
+		t.Fatalf("expected merged runs, got %d runs", len(s.values.runs))
+	}
+	for i := 0; i < n; i++ {
+		dup, err := s.Contains(strconv.Itoa(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dup != (i%2 == 0) {
+			t.Fatalf("key %d: duplicate %v, want %v", i, dup, i%2 == 0)
+		}
+	}
+	if s.Len() != n/2 {
+		t.Fatalf("Len is %d, want %d", s.Len(), n/2)
+	}
+	dir := s.values.dir
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("spill directory %s was not removed: %v", dir, err)
+	}
+}
+
+// TestDiskModeMatchesExact forces Bloom collisions on every lookup and
+// checks that the disk mode keeps exactly the values the exact mode keeps:
+// a new value wrongly rejected would be regenerated and change the output.
+func TestDiskModeMatchesExact(t *testing.T) {
+	defer func(keys int) { spillMemKeys = keys }(spillMemKeys)
+	spillMemKeys = 50
+
+	r, out := newTestRunner(t, uniqueConfig(3000, `    unique_retries: 1000`), 2500, Options{Workers: 4})
+	exact := runToLines(t, r, out)
+	r, out = newTestRunner(t, uniqueConfig(3000, `    unique_retries: 1000
+    unique_mode: "disk"
+    unique_false_positive_rate: 0.99`), 2500, Options{Workers: 4})
+	disk := runToLines(t, r, out)
+	if err := r.closeUnique(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(disk, exact) {
+		t.Fatal("disk mode output differs from exact mode")
+	}
+}
+
+func TestUniqueWithBlankRates(t *testing.T) {
+	const blanks = `
+seed: 3
+fields:
+  - name: "code"
+    unique: true
+    null_rate: 0.2
+    empty_rate: 0.2
+    generator:
+      type: "builtin"
+      settings:
+        function: "random_int"
+        min: 1
+        max: 1000000
+output:
+  type: "csv"
+`
+	r, out := newTestRunner(t, blanks, 200, Options{Workers: 4})
+	lines := runToLines(t, r, out)
+	seen := make(map[string]bool)
+	blank := 0
+	for _, line := range lines {
+		if line == "" || line == `""` {
+			blank++
+			continue
+		}
+		if seen[line] {
+			t.Fatalf("value %s repeats", line)
+		}
+		seen[line] = true
+	}
+	if blank < 40 {
+		t.Fatalf("expected about 80 null or empty values, got %d", blank)
+	}
+}
+
+func TestGenerateStopsOnError(t *testing.T) {
+	const failing = `
+fields:
+  - name: "broken"
+    generator:
+      type: "expression"
+      settings:
+        expression: "$random_int(5, 1)"
+output:
+  type: "csv"
+`
+	r, _ := newTestRunner(t, failing, 10_000, Options{Workers: 16})
+	defer r.writer.Close()
+	if err := r.generate(context.Background(), func(int64) {}); err == nil {
+		t.Fatal("expected an error from the failing expression")
+	}
+}
+
+func TestGenerateCancel(t *testing.T) {
+	r, _ := newTestRunner(t, allGeneratorsConfig, 1_000_000, Options{Workers: 16})
+	defer r.writer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	err := r.generate(ctx, func(processed int64) {
+		if processed == 100 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+const schemaConfig = `
+seed: 11
+output:
+  type: "csv"
+tables:
+  - name: "orders"
+    count: "2k"
+    fields:
+      - name: "user_id"
+        ref: "users.id"
+      - name: "lines"
+        type: "array"
+        min_items: 1
+        max_items: 3
+        fields:
+          - name: "sku"
+            ref: "products.sku"
+      - name: "session"
+        ref: "sessions.id"
+  - name: "sessions"
+    parent: "users"
+    per_parent:
+      max: 4
+    fields:
+      - name: "id"
+        generator:
+          type: "sequence"
+      - name: "user_id"
+        from: "parent.id"
+      - name: "label"
+        generator:
+          type: "expression"
+          settings:
+            expression: "#parent.id/$random_int(1, 9)"
+  - name: "users"
+    count: "50"
+    fields:
+      - name: "id"
+        generator:
+          type: "builtin"
+          settings:
+            function: "uuid_v4"
+  - name: "products"
+    count: "10"
+    fields:
+      - name: "sku"
+        generator:
+          type: "regex"
+          settings:
+            pattern: "SKU-[0-9]{6}"
+`
+
+// runSchema runs a schema as the CLI does, checks every table's header and
+// returns each table's data rows.
+func runSchema(t *testing.T, opts Options) map[string][]string {
+	t.Helper()
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte(schemaConfig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range cfg.Tables {
+		cfg.Tables[i].Output.File = filepath.Join(dir, cfg.Tables[i].Name+".csv")
+	}
+	opts.Headless = true
+	s, err := NewSchema(cfg, 100, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	headers := map[string]string{
+		"orders":   "user_id,lines.0.sku,lines.1.sku,lines.2.sku,session",
+		"sessions": "id,user_id,label",
+		"users":    "id",
+		"products": "sku",
+	}
+	rows := make(map[string][]string)
+	for _, tc := range cfg.Tables {
+		data, err := os.ReadFile(tc.Output.File)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if lines[0] != headers[tc.Name] {
+			t.Fatalf("%s header is %q, want %q", tc.Name, lines[0], headers[tc.Name])
+		}
+		rows[tc.Name] = lines[1:]
+	}
+	return rows
+}
+
+func TestSchemaReferences(t *testing.T) {
+	rows := runSchema(t, Options{Workers: 16, Unordered: true})
+	users := make(map[string]bool)
+	for _, line := range rows["users"] {
+		users[line] = true
+	}
+	products := make(map[string]bool)
+	for _, line := range rows["products"] {
+		products[line] = true
+	}
+	if len(rows["orders"]) != 2000 {
+		t.Fatalf("expected 2000 orders, got %d", len(rows["orders"]))
+	}
+	sessions := make(map[string]bool)
+	for _, line := range rows["sessions"] {
+		sessions[strings.Split(line, ",")[0]] = true
+	}
+	for _, line := range rows["orders"] {
+		cols := strings.Split(line, ",")
+		if !users[cols[0]] {
+			t.Fatalf("order references missing user %q", cols[0])
+		}
+		if !sessions[cols[len(cols)-1]] {
+			t.Fatalf("order references missing session %q", cols[len(cols)-1])
+		}
+		for _, sku := range cols[1 : len(cols)-1] {
+			if sku != "" && !products[sku] {
+				t.Fatalf("order references missing product %q", sku)
+			}
+		}
+	}
+
+	// Keys are collected by record index, so references do not depend on write order.
+	serial := runSchema(t, Options{Workers: 1})
+	got, want := rows["orders"], serial["orders"]
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatal("references differ between ordered and unordered runs")
+	}
+}
+
+func TestRefToNullableKey(t *testing.T) {
+	const nullable = `
+output:
+  type: "csv"
+tables:
+  - name: "orders"
+    fields:
+      - name: "user_id"
+        ref: "users.id"
+  - name: "users"
+    fields:
+      - name: "id"
+        null_rate: 0.5
+        generator:
+          type: "sequence"
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(nullable), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range cfg.Tables {
+		cfg.Tables[i].Output.File = filepath.Join(t.TempDir(), cfg.Tables[i].Name+".csv")
+	}
+	_, err = NewSchema(cfg, 10, Options{Workers: 1})
+	if err == nil || !strings.Contains(err.Error(), "null_rate") {
+		t.Fatalf("expected a null_rate error for the referenced key, got %v", err)
+	}
+}
+
+func TestChildTables(t *testing.T) {
+	rows := runSchema(t, Options{Workers: 16})
+	users := make(map[string]bool)
+	for _, line := range rows["users"] {
+		users[line] = true
+	}
+	perUser := make(map[string]int)
+	for i, line := range rows["sessions"] {
+		cols := strings.Split(line, ",")
+		// Child records are numbered consecutively across parents.
+		if cols[0] != strconv.Itoa(i+1) {
+			t.Fatalf("session %d has id %s", i+1, cols[0])
+		}
+		if !users[cols[1]] || !strings.HasPrefix(cols[2], cols[1]+"/") {
+			t.Fatalf("session %q does not belong to a generated user", line)
+		}
+		perUser[cols[1]]++
+	}
+	for user, n := range perUser {
+		if n > 4 {
+			t.Fatalf("user %s has %d sessions, expected at most 4", user, n)
+		}
+	}
+
+	// Counts and records depend only on the seed, not on the workers.
+	serial := runSchema(t, Options{Workers: 1})
+	if strings.Join(rows["sessions"], "\n") != strings.Join(serial["sessions"], "\n") {
+		t.Fatal("child records differ between serial and parallel runs")
+	}
+}
@@ -0,0 +1,243 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+)
+
+const (
+	spillFanout    = 4   // Runs of one level merged into a run of the next
+	spillBlockKeys = 512 // Keys per block; the first key of each block stays in memory
+)
+
+// spillMemKeys is how many keys a disk-mode set holds in memory before it
+// writes them to a run. Tests lower it to spill early.
+var spillMemKeys = 1 << 20
+
+// spillSet stores every key exactly, in memory up to memLimit keys and then
+// in sorted runs in temporary files. Runs are merged spillFanout at a time
+// into runs of the next level, so there are only a few per level and a
+// lookup reads one block from each.
+type spillSet struct {
+	memLimit int
+	mem      map[string]struct{}
+	dir      string // Holds the runs; created on the first spill
+	runs     []*spillRun
+	n        int64
+}
+
+// spillRun is a file of keys in sorted order, each prefixed with its length
+// as a uvarint.
+type spillRun struct {
+	f      *os.File
+	level  int
+	first  []string // First key of each block
+	offset []int64  // Start of each block, then the end of the file
+}
+
+func newSpillSet(memLimit int) *spillSet {
+	return &spillSet{memLimit: memLimit, mem: make(map[string]struct{})}
+}
+
+func (s *spillSet) contains(key string) (bool, error) {
+	if _, ok := s.mem[key]; ok {
+		return true, nil
+	}
+	for _, run := range s.runs {
+		if ok, err := run.contains(key); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// add records a key that is not in the set yet.
+func (s *spillSet) add(key string) error {
+	s.mem[key] = struct{}{}
+	s.n++
+	if len(s.mem) < s.memLimit {
+		return nil
+	}
+	keys := make([]string, 0, len(s.mem))
+	for k := range s.mem {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	run, err := s.writeRun(0, func() (string, bool, error) {
+		if len(keys) == 0 {
+			return "", false, nil
+		}
+		k := keys[0]
+		keys = keys[1:]
+		return k, true, nil
+	})
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+	clear(s.mem)
+	return s.compact()
+}
+
+// compact merges the runs of each level into one run of the next level
+// whenever a level holds spillFanout runs.
+func (s *spillSet) compact() error {
+	for level := 0; ; level++ {
+		var same, rest []*spillRun
+		for _, run := range s.runs {
+			if run.level == level {
+				same = append(same, run)
+			} else {
+				rest = append(rest, run)
+			}
+		}
+		if len(same) < spillFanout {
+			return nil
+		}
+		merged, err := s.merge(level+1, same)
+		if err != nil {
+			return err
+		}
+		for _, run := range same {
+			run.remove()
+		}
+		s.runs = append(rest, merged)
+	}
+}
+
+// merge writes the keys of runs, which never share a key, into one run.
+func (s *spillSet) merge(level int, runs []*spillRun) (*spillRun, error) {
+	readers := make([]*bufio.Reader, len(runs))
+	heads := make([]*string, len(runs))
+	for i, run := range runs {
+		readers[i] = bufio.NewReader(io.NewSectionReader(run.f, 0, run.offset[len(run.offset)-1]))
+	}
+	advance := func(i int) error {
+		key, ok, err := readKey(readers[i])
+		heads[i] = nil
+		if ok {
+			heads[i] = &key
+		}
+		return err
+	}
+	for i := range runs {
+		if err := advance(i); err != nil {
+			return nil, err
+		}
+	}
+	return s.writeRun(level, func() (string, bool, error) {
+		lowest := -1
+		for i, h := range heads {
+			if h != nil && (lowest < 0 || *h < *heads[lowest]) {
+				lowest = i
+			}
+		}
+		if lowest < 0 {
+			return "", false, nil
+		}
+		key := *heads[lowest]
+		return key, true, advance(lowest)
+	})
+}
+
+// writeRun writes the keys returned by next, in sorted order, to a new run.
+func (s *spillSet) writeRun(level int, next func() (string, bool, error)) (*spillRun, error) {
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "likha-unique-")
+		if err != nil {
+			return nil, fmt.Errorf("could not create spill directory: %w", err)
+		}
+		s.dir = dir
+	}
+	f, err := os.CreateTemp(s.dir, "run-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create spill file: %w", err)
+	}
+	run := &spillRun{f: f, level: level}
+	w := bufio.NewWriter(f)
+	var size [binary.MaxVarintLen64]byte
+	var off int64
+	for n := 0; ; n++ {
+		key, ok, err := next()
+		if err != nil {
+			run.remove()
+			return nil, fmt.Errorf("could not read spill file: %w", err)
+		}
+		if !ok {
+			break
+		}
+		if n%spillBlockKeys == 0 {
+			run.first = append(run.first, key)
+			run.offset = append(run.offset, off)
+		}
+		l := binary.PutUvarint(size[:], uint64(len(key)))
+		w.Write(size[:l])
+		w.WriteString(key)
+		off += int64(l + len(key))
+	}
+	run.offset = append(run.offset, off)
+	if err := w.Flush(); err != nil {
+		run.remove()
+		return nil, fmt.Errorf("could not write spill file: %w", err)
+	}
+	return run, nil
+}
+
+// close removes the runs and their directory.
+func (s *spillSet) close() error {
+	for _, run := range s.runs {
+		run.f.Close()
+	}
+	s.runs = nil
+	if s.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
+
+// contains reads the one block that may hold key.
+func (r *spillRun) contains(key string) (bool, error) {
+	b := sort.Search(len(r.first), func(i int) bool { return r.first[i] > key }) - 1
+	if b < 0 {
+		return false, nil
+	}
+	buf := make([]byte, r.offset[b+1]-r.offset[b])
+	if _, err := r.f.ReadAt(buf, r.offset[b]); err != nil {
+		return false, fmt.Errorf("could not read spill file: %w", err)
+	}
+	for len(buf) > 0 {
+		n, w := binary.Uvarint(buf)
+		k := string(buf[w : w+int(n)])
+		if k >= key {
+			return k == key, nil
+		}
+		buf = buf[w+int(n):]
+	}
+	return false, nil
+}
+
+func (r *spillRun) remove() {
+	r.f.Close()
+	os.Remove(r.f.Name())
+}
+
+// readKey reads the next key of a run, reporting false at its end.
+func readKey(r *bufio.Reader) (string, bool, error) {
+	n, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", false, err
+	}
+	return string(buf), true, nil
+}
@@ -0,0 +1,490 @@
+package runner
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"likha/config"
+	"likha/distribution"
+	"likha/generator/types"
+	"likha/util"
+)
+
+// scope holds what field generators can draw on besides their own settings.
+type scope struct {
+	entities []entityField
+	keys     keyResolver // Nil outside a multi-table schema
+	parent   []string    // Top-level fields of the parent table; nil outside child tables
+}
+
+// parentName is how a child table's fields refer to the parent record, as in
+// from: parent.id or #parent.order_date. The record is in the row under parentKey.
+const parentName = "parent"
+
+var parentKey = types.RowKey(parentName)
+
+// keyResolver returns the key column of a field of another table.
+type keyResolver func(table, field string) (*keyColumn, error)
+
+// keyColumn holds the value of a referenced field for every record of its
+// table, by index. It is filled as the table's rows are written.
+type keyColumn struct {
+	field  string
+	values []interface{}
+}
+
+// set stores the value of the record at position i of the run. The column
+// grows as needed, since a child table's record count is not known up front.
+func (k *keyColumn) set(i int64, v interface{}) {
+	for int64(len(k.values)) <= i {
+		k.values = append(k.values, nil)
+	}
+	k.values[i] = v
+}
+
+// refGenerator picks the key of a random record of another table. That table
+// is generated first, so every reference points to a row that exists.
+type refGenerator struct {
+	keys *keyColumn
+}
+
+// newRef creates the generator of a 'ref' field.
+func newRef(sc *scope, f config.Field) (types.Generator, error) {
+	if f.Generator.Type != "" || f.From != "" {
+		return nil, fmt.Errorf("use only one of 'ref', 'from' and 'generator'")
+	}
+	if sc.keys == nil {
+		return nil, fmt.Errorf("'ref' is only available between tables")
+	}
+	table, field, ok := parseRef(f.Ref)
+	if !ok {
+		return nil, fmt.Errorf("'ref' must have the form table.field, got '%s'", f.Ref)
+	}
+	keys, err := sc.keys(table, field)
+	if err != nil {
+		return nil, err
+	}
+	return &refGenerator{keys: keys}, nil
+}
+
+// Generate returns the key of a random record of the referenced table.
+func (g *refGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	if len(g.keys.values) == 0 {
+		return nil, fmt.Errorf("field '%s' cannot be referenced because its table has no records", g.keys.field)
+	}
+	return g.keys.values[ctx.Rand.IntN(len(g.keys.values))], nil
+}
+
+// parseRef splits a reference of the form table.field.
+func parseRef(ref string) (table, field string, ok bool) {
+	table, field, ok = strings.Cut(ref, ".")
+	return table, field, ok && table != "" && field != ""
+}
+
+// childTable is a table whose records are generated along with the records
+// of its parent table: per_parent decides how many each parent record gets,
+// and each child record can read its parent's fields.
+type childTable struct {
+	name     string
+	runner   *Runner
+	min, max int64
+	sampler  *distribution.Sampler // Nil for a uniform count
+	seed     int64                 // Seed of the per-parent counts
+	written  int64
+}
+
+// childBlock holds the child records of one parent record for one child table.
+type childBlock struct {
+	start int64 // Index of the first child record
+	count int64
+	rows  []map[string]interface{}
+}
+
+// newChildTable reads the per_parent settings of a child table.
+func newChildTable(t config.Table, runner *Runner, seed int64) (*childTable, error) {
+	c := &childTable{name: t.Name, runner: runner, seed: util.DeriveSeed(seed, "@per_parent")}
+	if v, ok := t.PerParent["min"]; ok {
+		n, ok := util.InterfaceToInt(v)
+		if !ok {
+			return nil, fmt.Errorf("per_parent: 'min' must be an integer")
+		}
+		c.min = int64(n)
+	}
+	v, ok := t.PerParent["max"]
+	if !ok {
+		return nil, fmt.Errorf("per_parent: 'max' is required")
+	}
+	n, ok := util.InterfaceToInt(v)
+	if !ok {
+		return nil, fmt.Errorf("per_parent: 'max' must be an integer")
+	}
+	c.max = int64(n)
+	if c.min < 0 || c.max < c.min {
+		return nil, fmt.Errorf("per_parent: requires 0 <= min <= max")
+	}
+	var err error
+	c.sampler, err = distribution.FromSettings(t.PerParent, float64(c.min), float64(c.max))
+	if err != nil {
+		return nil, fmt.Errorf("per_parent: %w", err)
+	}
+	return c, nil
+}
+
+// count returns the number of child records of the parent record at index.
+// It depends only on the seed and the index, like the records themselves.
+func (c *childTable) count(ctx *types.Context, index int64) int64 {
+	ctx.Reset(index, c.seed)
+	if c.sampler != nil {
+		return int64(math.Round(c.sampler.Sample(ctx.Rand)))
+	}
+	return c.min + ctx.Rand.Int64N(c.max-c.min+1)
+}
+
+// firstChildIndexes returns, per child table, the index of the first child
+// record of the run: the child records of the records before Options.Start
+// are counted, not generated. Each child runner starts there.
+func (r *Runner) firstChildIndexes(ctx *types.Context) []int64 {
+	first := make([]int64, len(r.children))
+	for j, c := range r.children {
+		for i := int64(0); i < r.opts.Start; i++ {
+			first[j] += c.count(ctx, i)
+		}
+		c.runner.opts.Start = first[j]
+	}
+	return first
+}
+
+// generateChildren generates the child records of parent into blocks.
+func (r *Runner) generateChildren(ctx *types.Context, blocks []childBlock, parent map[string]interface{}) error {
+	for j, c := range r.children {
+		b := &blocks[j]
+		b.rows = make([]map[string]interface{}, b.count)
+		for k := range b.rows {
+			row, err := c.runner.generateRecordAttempt(ctx, b.start+int64(k), nil, parent)
+			if err != nil {
+				return fmt.Errorf("table '%s': %w", c.name, err)
+			}
+			b.rows[k] = row
+		}
+	}
+	return nil
+}
+
+// writeChildren writes the child records of one record to their tables.
+func (r *Runner) writeChildren(blocks []childBlock) error {
+	for j, c := range r.children {
+		for k, row := range blocks[j].rows {
+			index := blocks[j].start + int64(k)
+			for _, key := range c.runner.keys {
+				key.set(index-c.runner.opts.Start, row[key.field])
+			}
+			if err := c.runner.writer.WriteRow(row); err != nil {
+				return fmt.Errorf("table '%s': failed to write row %d: %w", c.name, index, err)
+			}
+			c.written++
+		}
+	}
+	return nil
+}
+
+// Schema generates the tables of a multi-table config, each into its own
+// output. A table is generated after the tables it references; child tables
+// are generated along with their parent.
+type Schema struct {
+	tables []*schemaTable // Tables without a parent, in generation order
+}
+
+type schemaTable struct {
+	cfg    config.Table
+	count  int64
+	runner *Runner
+}
+
+// NewSchema creates the runners of all tables. count is the record count of
+// tables that do not set their own. opts applies to every table.
+func NewSchema(cfg *config.Config, count int64, opts Options) (*Schema, error) {
+	if len(cfg.Tables) == 0 {
+		return nil, fmt.Errorf("the config defines no tables")
+	}
+	if len(cfg.Fields) > 0 || len(cfg.Entities) > 0 {
+		return nil, fmt.Errorf("use either top-level fields and entities or tables, not both")
+	}
+
+	byName := make(map[string]*schemaTable, len(cfg.Tables))
+	var tables []*schemaTable
+	for _, t := range cfg.Tables {
+		if t.Name == "" {
+			return nil, fmt.Errorf("every table needs a name")
+		}
+		if byName[t.Name] != nil {
+			return nil, fmt.Errorf("table '%s' is defined twice", t.Name)
+		}
+		st := &schemaTable{cfg: t, count: count}
+		if t.Parent != "" {
+			st.count = 0
+			if t.Count != "" {
+				return nil, fmt.Errorf("table '%s': a child table takes its record count from per_parent, not count", t.Name)
+			}
+			for _, f := range t.Fields {
+				if f.Unique {
+					return nil, fmt.Errorf("table '%s', field '%s': unique is not supported in child tables", t.Name, f.Name)
+				}
+			}
+		} else if t.PerParent != nil {
+			return nil, fmt.Errorf("table '%s': per_parent requires a parent", t.Name)
+		} else if t.Count != "" {
+			n, err := util.ParseCount(t.Count)
+			if err != nil {
+				return nil, fmt.Errorf("table '%s': invalid count: %w", t.Name, err)
+			}
+			st.count = n
+		}
+		byName[t.Name] = st
+		tables = append(tables, st)
+	}
+	for _, st := range tables {
+		parent := st.cfg.Parent
+		if parent == "" {
+			continue
+		}
+		switch {
+		case parent == st.cfg.Name:
+			return nil, fmt.Errorf("table '%s' cannot be its own parent", parent)
+		case byName[parent] == nil:
+			return nil, fmt.Errorf("table '%s': unknown parent table '%s'", st.cfg.Name, parent)
+		case byName[parent].cfg.Parent != "":
+			return nil, fmt.Errorf("table '%s': parent table '%s' is itself a child table; only one level is supported", st.cfg.Name, parent)
+		}
+	}
+
+	order, err := dependencyOrder(tables, byName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without an explicit seed the output is random; each table derives its
+	// own seed from one chosen for the whole run.
+	seed := time.Now().UnixNano()
+	if cfg.Seed != nil {
+		seed = *cfg.Seed
+	}
+
+	// keys resolves references. Tables are created in generation order, so
+	// the referenced table's runner already exists.
+	keys := func(table, field string) (*keyColumn, error) {
+		parent := byName[table]
+		if parent == nil {
+			return nil, fmt.Errorf("unknown table '%s'", table)
+		}
+		k, err := parent.runner.keyColumn(field)
+		if err != nil {
+			return nil, fmt.Errorf("table '%s': %w", table, err)
+		}
+		return k, nil
+	}
+
+	// Each root table is created with its child tables, which are given the
+	// parent's top-level fields and attached to its runner.
+	create := func(st *schemaTable, parent *Runner) error {
+		t := st.cfg
+		tableSeed := util.DeriveSeed(seed, t.Name)
+		if t.Seed != nil {
+			tableSeed = *t.Seed
+		}
+		tcfg := &config.Config{
+			Seed:     &tableSeed,
+			Locale:   cfg.Locale,
+			Entities: t.Entities,
+			Fields:   t.Fields,
+			Output:   tableOutput(t, cfg.Output),
+		}
+		sc := &scope{keys: keys}
+		if parent != nil {
+			sc.parent = parent.fieldOrder
+		}
+		runner, err := newRunner(tcfg, st.count, opts, sc)
+		if err != nil {
+			return fmt.Errorf("table '%s': %w", t.Name, err)
+		}
+		st.runner = runner
+		if parent == nil {
+			return nil
+		}
+		runner.child = true
+		c, err := newChildTable(t, runner, tableSeed)
+		if err != nil {
+			runner.closeWriter(nil)
+			return fmt.Errorf("table '%s': %w", t.Name, err)
+		}
+		parent.children = append(parent.children, c)
+		return nil
+	}
+	for _, st := range order {
+		err = create(st, nil)
+		for _, child := range tables {
+			if err == nil && child.cfg.Parent == st.cfg.Name {
+				err = create(child, st.runner)
+			}
+		}
+		if err != nil {
+			for _, created := range order {
+				if created.runner != nil {
+					created.runner.closeWriter(nil)
+				}
+			}
+			return nil, err
+		}
+	}
+	return &Schema{tables: order}, nil
+}
+
+// tableOutput completes a table's output from the top-level output: the type
+// and settings default to the top-level ones, and the file to <name>.<type>.
+func tableOutput(t config.Table, defaults config.OutputConfig) config.OutputConfig {
+	out := t.Output
+	if out.Type == "" {
+		out.Type = defaults.Type
+	}
+	if out.Settings == nil {
+		out.Settings = defaults.Settings
+	}
+	if out.File == "" {
+		out.File = t.Name + "." + out.Type
+	}
+	return out
+}
+
+// dependencyOrder sorts the tables without a parent so that each comes after
+// the tables it references, keeping the config order otherwise. A child
+// table's references count as its parent's.
+func dependencyOrder(all []*schemaTable, byName map[string]*schemaTable) ([]*schemaTable, error) {
+	root := func(st *schemaTable) string {
+		if st.cfg.Parent != "" {
+			return st.cfg.Parent
+		}
+		return st.cfg.Name
+	}
+	var tables []*schemaTable
+	deps := make(map[string][]string, len(all))
+	for _, st := range all {
+		if st.cfg.Parent == "" {
+			tables = append(tables, st)
+		}
+		refs, err := tableRefs(st.cfg.Name, st.cfg.Fields, byName)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			if root(byName[ref]) == root(st) {
+				return nil, fmt.Errorf("table '%s' cannot reference table '%s', which is generated along with it", st.cfg.Name, ref)
+			}
+			deps[root(st)] = append(deps[root(st)], root(byName[ref]))
+		}
+	}
+
+	done := make(map[string]bool, len(tables))
+	var order []*schemaTable
+	for len(order) < len(tables) {
+		progressed := false
+		for _, st := range tables {
+			if done[st.cfg.Name] {
+				continue
+			}
+			ready := true
+			for _, d := range deps[st.cfg.Name] {
+				ready = ready && done[d]
+			}
+			if ready {
+				done[st.cfg.Name] = true
+				order = append(order, st)
+				progressed = true
+			}
+		}
+		if !progressed {
+			var cycle []string
+			for _, st := range tables {
+				if !done[st.cfg.Name] {
+					cycle = append(cycle, st.cfg.Name)
+				}
+			}
+			return nil, fmt.Errorf("tables %s reference each other in a cycle", strings.Join(cycle, ", "))
+		}
+	}
+	return order, nil
+}
+
+// tableRefs lists the tables that the fields of a table reference, including from nested fields.
+func tableRefs(table string, fields []config.Field, byName map[string]*schemaTable) ([]string, error) {
+	var refs []string
+	for _, f := range fields {
+		nested, err := tableRefs(table, f.Fields, byName)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, nested...)
+		if f.Ref == "" {
+			continue
+		}
+		parent, _, ok := parseRef(f.Ref)
+		if !ok {
+			return nil, fmt.Errorf("table '%s', field '%s': 'ref' must have the form table.field, got '%s'", table, f.Name, f.Ref)
+		}
+		if parent == table {
+			return nil, fmt.Errorf("table '%s', field '%s': a table cannot reference itself", table, f.Name)
+		}
+		if byName[parent] == nil {
+			return nil, fmt.Errorf("table '%s', field '%s': unknown table '%s'", table, f.Name, parent)
+		}
+		refs = append(refs, parent)
+	}
+	return refs, nil
+}
+
+// keyColumn returns the column that collects a top-level field's values for
+// references from other tables, creating it on first use.
+func (r *Runner) keyColumn(field string) (*keyColumn, error) {
+	for _, k := range r.keys {
+		if k.field == field {
+			return k, nil
+		}
+	}
+	pos := -1
+	for i, name := range r.fieldOrder {
+		if name == field {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		return nil, fmt.Errorf("no top-level field '%s' to reference", field)
+	}
+	f := r.config.Fields[pos]
+	if isNested(f.Type) {
+		return nil, fmt.Errorf("field '%s' is an %s and cannot be referenced", field, f.Type)
+	}
+	// A blank key would leave the referencing record without a parent row.
+	if f.NullRate > 0 || f.EmptyRate > 0 {
+		return nil, fmt.Errorf("field '%s' has a null_rate or empty_rate and cannot be referenced", field)
+	}
+	if r.count == 0 && !r.child {
+		return nil, fmt.Errorf("field '%s' cannot be referenced because the table has no records", field)
+	}
+	k := &keyColumn{field: field, values: make([]interface{}, r.count)}
+	r.keys = append(r.keys, k)
+	return k, nil
+}
+
+// Run generates every table in dependency order, stopping at the first error.
+func (s *Schema) Run() error {
+	for i, st := range s.tables {
+		if err := st.runner.Run(); err != nil {
+			// Tables that did not run still hold an open output.
+			for _, rest := range s.tables[i+1:] {
+				rest.runner.closeWriter(nil)
+			}
+			return fmt.Errorf("table '%s': %w", st.cfg.Name, err)
+		}
+	}
+	return nil
+}
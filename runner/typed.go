@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	"likha/config"
+	"likha/generator/types"
+	"likha/value"
+)
+
+// typedGenerator converts the values of a field's generator to the field's type.
+type typedGenerator struct {
+	gen   types.Generator
+	kind  string
+	scale int // Decimal places, or -1 to keep the value's own
+}
+
+// isNested reports whether a field type holds child values rather than naming a value type.
+func isNested(kind string) bool {
+	return kind == "object" || kind == "array"
+}
+
+// withType wraps gen so its values are converted to the field's type. Fields
+// without a value type get gen back unchanged.
+func withType(f config.Field, gen types.Generator) (types.Generator, error) {
+	if f.Type == "" || isNested(f.Type) {
+		if f.Scale != nil {
+			return nil, fmt.Errorf("'scale' requires type decimal")
+		}
+		return gen, nil
+	}
+	if !value.IsKind(f.Type) {
+		return nil, fmt.Errorf("unknown field type '%s' (expected object, array, %s)", f.Type, strings.Join(value.Kinds, ", "))
+	}
+	g := &typedGenerator{gen: gen, kind: f.Type, scale: -1}
+	if f.Scale != nil {
+		if f.Type != "decimal" {
+			return nil, fmt.Errorf("'scale' requires type decimal")
+		}
+		if *f.Scale < 0 {
+			return nil, fmt.Errorf("'scale' must not be negative")
+		}
+		g.scale = *f.Scale
+	}
+	return g, nil
+}
+
+// Generate converts the generated value.
+func (g *typedGenerator) Generate(ctx *types.Context, row map[string]interface{}) (interface{}, error) {
+	v, err := g.gen.Generate(ctx, row)
+	if err != nil {
+		return nil, err
+	}
+	return value.Coerce(v, g.kind, g.scale)
+}
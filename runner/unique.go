@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+
+	"likha/config"
+	"likha/generator/types"
+	"likha/util"
+)
+
+// defaultUniqueRetries is how many times a duplicate value is regenerated before giving up.
+const defaultUniqueRetries = 100
+
+// uniqueSet remembers the values already emitted for a unique field.
+type uniqueSet interface {
+	// Contains reports whether key may have been added before.
+	Contains(key string) (bool, error)
+	// Add records key.
+	Add(key string) error
+	// Len returns the number of keys added.
+	Len() int64
+	// Close releases any files the set keeps.
+	Close() error
+}
+
+// uniqueField tracks the uniqueness constraint of one field.
+type uniqueField struct {
+	pos     int // Position in fieldOrder
+	slot    int // Position in the attempts of generateRecordAttempt to bump on a duplicate
+	name    string
+	retries int
+	set     uniqueSet
+}
+
+// newUniqueField creates the constraint for f, sizing Bloom filters for count records.
+func newUniqueField(pos int, f config.Field, count int64) (*uniqueField, error) {
+	retries := defaultUniqueRetries
+	if f.UniqueRetries > 0 {
+		retries = f.UniqueRetries
+	}
+
+	var set uniqueSet
+	switch f.UniqueMode {
+	case "", "exact":
+		set = &exactSet{keys: make(map[string]struct{})}
+	case "hash":
+		set = &hashSet{keys: make(map[uint64]struct{})}
+	case "disk":
+		set = &diskSet{filter: newBloomFilter(count, f.UniqueFalsePositiveRate), values: newSpillSet(spillMemKeys)}
+	default:
+		return nil, fmt.Errorf("unknown unique_mode '%s' (expected exact, hash or disk)", f.UniqueMode)
+	}
+	return &uniqueField{pos: pos, slot: pos, name: f.Name, retries: retries, set: set}, nil
+}
+
+// ensureUnique checks the unique fields of a generated row and regenerates
+// the record until every unique field holds a value not seen before. Each
+// retry reseeds only the offending field, so rows stay reproducible as long
+// as they are checked in the same order. Null and empty values are never
+// considered duplicates, so a unique field can still use null_rate and
+// empty_rate. It also reports whether the row was regenerated.
+func (r *Runner) ensureUnique(ctx *types.Context, index int64, row map[string]interface{}) (map[string]interface{}, bool, error) {
+	var attempts []int
+	for {
+		dup, err := r.findDuplicate(row)
+		if err != nil {
+			return nil, false, err
+		}
+		if dup == nil {
+			for _, uf := range r.uniqueFields {
+				if v := row[uf.name]; !isBlank(v) {
+					if err := uf.set.Add(fmt.Sprintf("%v", v)); err != nil {
+						return nil, false, fmt.Errorf("field '%s': %w", uf.name, err)
+					}
+				}
+			}
+			return row, attempts != nil, nil
+		}
+
+		if attempts == nil {
+			attempts = make([]int, len(r.fieldOrder)+len(r.entities))
+		}
+		attempts[dup.slot]++
+		if attempts[dup.slot] > dup.retries {
+			return nil, false, fmt.Errorf("field '%s': no unique value found after %d retries (%d unique values so far); the value space may be exhausted",
+				dup.name, dup.retries, dup.set.Len())
+		}
+
+		row, err = r.generateRecordAttempt(ctx, index, attempts, nil)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+// findDuplicate returns the first unique field whose value in row was already emitted.
+func (r *Runner) findDuplicate(row map[string]interface{}) (*uniqueField, error) {
+	for _, uf := range r.uniqueFields {
+		v := row[uf.name]
+		if isBlank(v) {
+			continue
+		}
+		dup, err := uf.set.Contains(fmt.Sprintf("%v", v))
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %w", uf.name, err)
+		}
+		if dup {
+			return uf, nil
+		}
+	}
+	return nil, nil
+}
+
+// closeUnique releases the files of the unique sets.
+func (r *Runner) closeUnique() error {
+	var err error
+	for _, uf := range r.uniqueFields {
+		if cerr := uf.set.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("field '%s': %w", uf.name, cerr)
+		}
+	}
+	return err
+}
+
+// isBlank reports whether v is null or the empty string.
+func isBlank(v interface{}) bool {
+	return v == nil || v == ""
+}
+
+// retrySeed derives the seed used for a field on its nth uniqueness retry.
+func retrySeed(seed int64, attempt int) int64 {
+	return int64(util.Mix64(uint64(seed) ^ util.Mix64(uint64(attempt))))
+}
+
+// exactSet stores every value. It never reports false duplicates.
+type exactSet struct {
+	keys map[string]struct{}
+}
+
+func (s *exactSet) Contains(key string) (bool, error) {
+	_, ok := s.keys[key]
+	return ok, nil
+}
+
+func (s *exactSet) Add(key string) error {
+	s.keys[key] = struct{}{}
+	return nil
+}
+
+func (s *exactSet) Len() int64 { return int64(len(s.keys)) }
+
+func (s *exactSet) Close() error { return nil }
+
+// hashSet stores a 64-bit hash of each value instead of the value itself,
+// which keeps long strings cheap. A hash collision is reported as a
+// duplicate, which only costs an extra retry.
+type hashSet struct {
+	keys map[uint64]struct{}
+}
+
+func (s *hashSet) Contains(key string) (bool, error) {
+	_, ok := s.keys[hashKey(key)]
+	return ok, nil
+}
+
+func (s *hashSet) Add(key string) error {
+	s.keys[hashKey(key)] = struct{}{}
+	return nil
+}
+
+func (s *hashSet) Len() int64 { return int64(len(s.keys)) }
+
+func (s *hashSet) Close() error { return nil }
+
+// diskSet is the disk mode: a Bloom filter in memory in front of a spillSet
+// holding every value. Only values the filter may have seen are looked up
+// on disk, and a false positive of the filter costs one lookup, never a
+// retry, so no new value is ever reported as a duplicate.
+type diskSet struct {
+	filter *bloomFilter
+	values *spillSet
+}
+
+func (s *diskSet) Contains(key string) (bool, error) {
+	if !s.filter.contains(key) {
+		return false, nil
+	}
+	return s.values.contains(key)
+}
+
+func (s *diskSet) Add(key string) error {
+	s.filter.add(key)
+	return s.values.add(key)
+}
+
+func (s *diskSet) Len() int64 { return s.values.n }
+
+func (s *diskSet) Close() error { return s.values.close() }
+
+// bloomFilter is a fixed-size Bloom filter. It never misses a key that was
+// added, and reports a key that was not about as often as its false
+// positive rate.
+type bloomFilter struct {
+	bits []uint64
+	m    uint64 // Number of bits
+	k    int    // Number of hash functions
+}
+
+// newBloomFilter sizes a filter for n keys at the given false positive rate (default 0.1%).
+func newBloomFilter(n int64, fpRate float64) *bloomFilter {
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.001
+	}
+	if n < 1000 {
+		n = 1000
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// positions yields the k bit positions of key using double hashing.
+func (f *bloomFilter) positions(key string, fn func(pos uint64) bool) {
+	h1 := hashKey(key)
+	h2 := util.Mix64(h1) | 1
+	for i := 0; i < f.k; i++ {
+		if !fn((h1 + uint64(i)*h2) % f.m) {
+			return
+		}
+	}
+}
+
+func (f *bloomFilter) contains(key string) bool {
+	found := true
+	f.positions(key, func(pos uint64) bool {
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			found = false
+		}
+		return found
+	})
+	return found
+}
+
+func (f *bloomFilter) add(key string) {
+	f.positions(key, func(pos uint64) bool {
+		f.bits[pos/64] |= 1 << (pos % 64)
+		return true
+	})
+}
+
+// hashKey hashes a value with a fixed function, so runs stay reproducible.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return util.Mix64(h.Sum64())
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // ParseCount converts a user-friendly string like "10k", "10m", "10b" to an int64.
@@ -31,6 +32,24 @@ func ParseCount(s string) (int64, error) {
 	return val * multiplier, nil
 }
 
+// ParseDuration reads a duration such as "90s", "5m" or "1h30m". It also
+// accepts a number of days, e.g. "7d" or "0.5d".
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		f, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: '%s'", s)
+		}
+		return time.Duration(f * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: '%s'", s)
+	}
+	return d, nil
+}
+
 // InterfaceToInt safely converts an interface{} to int.
 // It's useful for parsing settings from the YAML config.
 func InterfaceToInt(v interface{}) (int, bool) {
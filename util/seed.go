@@ -0,0 +1,21 @@
+package util
+
+import "hash/fnv"
+
+// Mix64 scrambles a 64-bit value using the SplitMix64 finalizer.
+// It is used to turn structured inputs (seeds, indexes, hashes) into
+// well-distributed RNG seeds.
+func Mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// DeriveSeed derives a child seed from a parent seed and a name, so that every
+// field (or sub-generator) gets its own independent but reproducible stream.
+func DeriveSeed(seed int64, name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(Mix64(uint64(seed) ^ Mix64(h.Sum64())))
+}
@@ -0,0 +1,167 @@
+package uuid
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// UUID is an RFC 9562 universally unique identifier.
+type UUID [16]byte
+
+// Well-known namespaces for name-based (v5) UUIDs.
+var (
+	NamespaceDNS  = MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceURL  = MustParse("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceOID  = MustParse("6ba7b812-9dad-11d1-80b4-00c04fd430c8")
+	NamespaceX500 = MustParse("6ba7b814-9dad-11d1-80b4-00c04fd430c8")
+)
+
+// crockford is the Base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewV4 returns a random UUID drawn from r.
+func NewV4(r *rand.Rand) UUID {
+	var u UUID
+	fillRandom(r, u[:])
+	u.setVersion(4)
+	return u
+}
+
+// NewV7 returns a time-ordered UUID embedding the millisecond timestamp t,
+// with the remaining bits drawn from r.
+func NewV7(r *rand.Rand, t time.Time) UUID {
+	var u UUID
+	fillRandom(r, u[6:])
+	putMillis(u[:6], t)
+	u.setVersion(7)
+	return u
+}
+
+// NewV5 returns the name-based UUID for name within namespace (SHA-1).
+// The same inputs always produce the same UUID.
+func NewV5(namespace UUID, name string) UUID {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	var u UUID
+	copy(u[:], h.Sum(nil))
+	u.setVersion(5)
+	return u
+}
+
+// NewULID returns a 26-character ULID embedding the millisecond timestamp t,
+// with 80 random bits drawn from r.
+func NewULID(r *rand.Rand, t time.Time) string {
+	var b [16]byte
+	putMillis(b[:6], t)
+	fillRandom(r, b[6:])
+
+	// Encode the 128 bits as 26 Base32 digits, most significant first.
+	// The first digit only carries 3 bits.
+	hi := uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32 |
+		uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7])
+	lo := uint64(b[8])<<56 | uint64(b[9])<<48 | uint64(b[10])<<40 | uint64(b[11])<<32 |
+		uint64(b[12])<<24 | uint64(b[13])<<16 | uint64(b[14])<<8 | uint64(b[15])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// IndexMillis returns the timestamp of the time-ordered ID of record index:
+// start plus index steps, whole milliseconds each, so IDs sort in record
+// order. Records that would fall after end are an error.
+func IndexMillis(index int64, start, end time.Time, step time.Duration) (time.Time, error) {
+	ms := step.Milliseconds()
+	if ms < 1 {
+		return time.Time{}, fmt.Errorf("step must be at least 1ms, got %s", step)
+	}
+	if index < 0 || index > (end.UnixMilli()-start.UnixMilli())/ms {
+		return time.Time{}, fmt.Errorf("record %d falls after the end of the time range; use a shorter step or a later end", index)
+	}
+	return time.UnixMilli(start.UnixMilli() + index*ms), nil
+}
+
+// Parse parses a UUID in its canonical 8-4-4-4-12 hex form.
+func Parse(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("invalid UUID %q", s)
+	}
+	raw := strings.ReplaceAll(s, "-", "")
+	if _, err := hex.Decode(u[:], []byte(raw)); err != nil {
+		return u, fmt.Errorf("invalid UUID %q: %w", s, err)
+	}
+	return u, nil
+}
+
+// MustParse is like Parse but panics on error. It is meant for constants.
+func MustParse(s string) UUID {
+	u, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// ParseNamespace resolves a v5 namespace given as "dns", "url", "oid", "x500" or a UUID string.
+func ParseNamespace(s string) (UUID, error) {
+	switch strings.ToLower(s) {
+	case "dns":
+		return NamespaceDNS, nil
+	case "url":
+		return NamespaceURL, nil
+	case "oid":
+		return NamespaceOID, nil
+	case "x500":
+		return NamespaceX500, nil
+	}
+	return Parse(s)
+}
+
+// String returns the canonical 8-4-4-4-12 lowercase hex form.
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// setVersion stamps the version nibble and the RFC 9562 variant bits.
+func (u *UUID) setVersion(v byte) {
+	u[6] = u[6]&0x0f | v<<4
+	u[8] = u[8]&0x3f | 0x80
+}
+
+// putMillis writes t as a 48-bit big-endian Unix millisecond timestamp.
+func putMillis(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+func fillRandom(r *rand.Rand, b []byte) {
+	for i := 0; i < len(b); i += 8 {
+		v := r.Uint64()
+		for j := i; j < len(b) && j < i+8; j++ {
+			b[j] = byte(v)
+			v >>= 8
+		}
+	}
+}
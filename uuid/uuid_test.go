@@ -0,0 +1,119 @@
+package uuid
+
+import (
+	"math/rand/v2"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestVersionAndVariant(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		for _, tt := range []struct {
+			version byte
+			u       UUID
+		}{
+			{4, NewV4(r)},
+			{7, NewV7(r, now)},
+			{5, NewV5(NamespaceURL, string(rune('a'+i%26)))},
+		} {
+			if got := tt.u[6] >> 4; got != tt.version {
+				t.Fatalf("%s: version %d, want %d", tt.u, got, tt.version)
+			}
+			if got := tt.u[8] >> 6; got != 0b10 {
+				t.Fatalf("%s: variant bits %02b, want 10", tt.u, got)
+			}
+		}
+	}
+}
+
+func TestV5Vectors(t *testing.T) {
+	tests := []struct {
+		namespace UUID
+		name      string
+		want      string
+	}{
+		{NamespaceDNS, "www.example.com", "2ed6657d-e927-568b-95e1-2665a8aea6a2"},
+		{NamespaceDNS, "python.org", "886313e1-3b8a-5372-9b90-0c9aee199e5d"},
+	}
+	for _, tt := range tests {
+		if got := NewV5(tt.namespace, tt.name).String(); got != tt.want {
+			t.Errorf("NewV5(%s, %q) = %s, want %s", tt.namespace, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestV7Timestamp(t *testing.T) {
+	ts := time.UnixMilli(0x0123456789ab)
+	u := NewV7(rand.New(rand.NewPCG(1, 2)), ts)
+	if got := u.String()[:13]; got != "01234567-89ab" {
+		t.Fatalf("timestamp prefix %s, want 01234567-89ab", got)
+	}
+}
+
+func TestULIDOrdering(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	shape := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		id := NewULID(r, start.Add(time.Duration(i)*time.Millisecond))
+		if !shape.MatchString(id) {
+			t.Fatalf("%q is not a ULID", id)
+		}
+		if id <= prev {
+			t.Fatalf("%s sorts before the earlier %s", id, prev)
+		}
+		prev = id
+	}
+	if got := NewULID(r, time.UnixMilli(0))[:10]; got != "0000000000" {
+		t.Fatalf("the epoch encodes as %s, want 0000000000", got)
+	}
+}
+
+func TestIndexMillis(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Second)
+	tests := []struct {
+		index int64
+		step  time.Duration
+		want  time.Time
+	}{
+		{0, time.Millisecond, start},
+		{7, time.Millisecond, start.Add(7 * time.Millisecond)},
+		{1000, time.Millisecond, end},
+		{2, 250 * time.Millisecond, start.Add(500 * time.Millisecond)},
+		{3, 1500 * time.Microsecond, start.Add(3 * time.Millisecond)},
+	}
+	for _, tt := range tests {
+		got, err := IndexMillis(tt.index, start, end, tt.step)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("IndexMillis(%d, step %s) = %v, %v, want %v", tt.index, tt.step, got, err, tt.want)
+		}
+	}
+	for _, bad := range []struct {
+		index int64
+		step  time.Duration
+	}{{1001, time.Millisecond}, {-1, time.Millisecond}, {5, 0}, {5, time.Microsecond}} {
+		if _, err := IndexMillis(bad.index, start, end, bad.step); err == nil {
+			t.Errorf("IndexMillis(%d, step %s) should fail", bad.index, bad.step)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	const s = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	if got := MustParse(s).String(); got != s {
+		t.Fatalf("round trip gave %s", got)
+	}
+	for _, bad := range []string{"", "6ba7b8109dad11d180b400c04fd430c8", "6ba7b810-9dad-11d1-80b4-00c04fd430cz"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+	if u, err := ParseNamespace("DNS"); err != nil || u != NamespaceDNS {
+		t.Errorf("ParseNamespace(DNS) = %s, %v", u, err)
+	}
+}
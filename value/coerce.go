@@ -0,0 +1,216 @@
+package value
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds lists the kinds a field's value can be coerced to, and the Go types
+// that hold them: int64, float64, Decimal, bool, time.Time, Date, string and
+// []byte. Null is nil in every kind.
+var Kinds = []string{"int", "float", "decimal", "bool", "timestamp", "date", "string", "bytes"}
+
+// DefaultScale is the number of decimal places of a decimal when neither
+// the field nor the value sets one.
+const DefaultScale = 2
+
+// IsKind reports whether kind is one of Kinds.
+func IsKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Coerce converts v to the given kind. scale sets the decimal places of a
+// decimal; when negative, a Decimal keeps its own and other values get
+// DefaultScale. Null stays null, as does an empty string for kinds other
+// than string and bytes.
+func Coerce(v interface{}, kind string, scale int) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if s, ok := v.(string); ok && s == "" && kind != "string" && kind != "bytes" {
+		return nil, nil
+	}
+	var out interface{}
+	var ok bool
+	switch kind {
+	case "int":
+		i, err := toInt(v)
+		if err != nil {
+			return nil, err
+		}
+		out, ok = i, true
+	case "float":
+		out, ok = toFloat(v)
+	case "decimal":
+		var f float64
+		if f, ok = toFloat(v); ok {
+			if scale < 0 {
+				scale = DefaultScale
+				if d, isDec := v.(Decimal); isDec {
+					scale = d.Scale
+				}
+			}
+			out = NewDecimal(f, scale)
+		}
+	case "bool":
+		out, ok = toBool(v)
+	case "timestamp":
+		out, ok = ToTime(v)
+	case "date":
+		var t time.Time
+		if t, ok = ToTime(v); ok {
+			out = NewDate(t)
+		}
+	case "string":
+		out, ok = Text(v), true
+	case "bytes":
+		switch x := v.(type) {
+		case []byte:
+			out, ok = x, true
+		default:
+			out, ok = []byte(Text(v)), true
+		}
+	default:
+		return nil, fmt.Errorf("unknown type '%s' (expected %s)", kind, strings.Join(Kinds, ", "))
+	}
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T '%s' to %s", v, Text(v), kind)
+	}
+	return out, nil
+}
+
+// toInt passes integers through unchanged and parses integer strings
+// exactly. Other numbers are rounded, and rejected outside the int64 range.
+func toInt(v interface{}) (int64, error) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), nil
+	case int8:
+		return int64(x), nil
+	case int16:
+		return int64(x), nil
+	case int32:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case uint8:
+		return int64(x), nil
+	case uint16:
+		return int64(x), nil
+	case uint32:
+		return int64(x), nil
+	case uint:
+		if uint64(x) <= math.MaxInt64 {
+			return int64(x), nil
+		}
+	case uint64:
+		if x <= math.MaxInt64 {
+			return int64(x), nil
+		}
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(x), 10, 64); err == nil {
+			return i, nil
+		}
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return 0, fmt.Errorf("cannot convert %T '%s' to int", v, Text(v))
+	}
+	f = math.Round(f)
+	// -2^63 is exactly representable; 2^63 is the first float past MaxInt64.
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("%T '%s' is outside the range of int", v, Text(v))
+	}
+	return int64(f), nil
+}
+
+// toFloat reads numbers, decimals, booleans, numeric strings and timestamps (as Unix seconds).
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int:
+		return float64(x), true
+	case int8:
+		return float64(x), true
+	case int16:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint:
+		return float64(x), true
+	case uint8:
+		return float64(x), true
+	case uint16:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	case Decimal:
+		return x.Float, true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	case time.Time:
+		return float64(x.Unix()), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toBool reads booleans, numbers (non-zero is true) and true/false, yes/no, on/off or 1/0 strings.
+func toBool(v interface{}) (bool, bool) {
+	if b, ok := v.(bool); ok {
+		return b, true
+	}
+	if s, ok := v.(string); ok {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "true", "t", "yes", "y", "on", "1":
+			return true, true
+		case "false", "f", "no", "n", "off", "0":
+			return false, true
+		}
+		return false, false
+	}
+	f, ok := toFloat(v)
+	return f != 0, ok
+}
+
+// ToTime reads timestamps, dates, strings accepted by ParseTime and Unix seconds.
+func ToTime(v interface{}) (time.Time, bool) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, true
+	case Date:
+		return time.Time(x), true
+	case bool:
+		return time.Time{}, false
+	case string:
+		if t, _, err := ParseTime(x); err == nil {
+			return t, true
+		}
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return time.Time{}, false
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+}
@@ -0,0 +1,85 @@
+package value
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Decimal is a number with a fixed number of decimal places, e.g. a price.
+// It is always written with exactly Scale places, so 12.3 at scale 2 is 12.30.
+type Decimal struct {
+	Float float64
+	Scale int
+}
+
+// NewDecimal rounds f to scale decimal places.
+func NewDecimal(f float64, scale int) Decimal {
+	p := math.Pow10(scale)
+	return Decimal{Float: math.Round(f*p) / p, Scale: scale}
+}
+
+// String formats the decimal with exactly Scale places.
+func (d Decimal) String() string {
+	return strconv.FormatFloat(d.Float, 'f', d.Scale, 64)
+}
+
+// MarshalJSON writes the decimal as a JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Date is a calendar date without a time of day.
+type Date time.Time
+
+// NewDate returns the date of t.
+func NewDate(t time.Time) Date {
+	return Date(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+}
+
+// String formats the date as YYYY-MM-DD.
+func (d Date) String() string {
+	return time.Time(d).Format(time.DateOnly)
+}
+
+// MarshalJSON writes the date as a YYYY-MM-DD string.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// Text renders a value for text formats such as CSV. Null is empty,
+// timestamps are RFC 3339 and bytes are base64.
+func Text(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(x)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// timeLayouts are the text forms ParseTime accepts.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", time.DateOnly}
+
+// ParseTime reads an RFC 3339 timestamp, a timestamp without a zone (taken
+// as UTC) or a plain YYYY-MM-DD date. It also returns the layout that
+// matched, so a changed time can be written back in the same form.
+func ParseTime(s string) (time.Time, string, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, layout, nil
+		}
+	}
+	return time.Time{}, "", fmt.Errorf("cannot read '%s' as a date or timestamp", s)
+}
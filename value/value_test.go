@@ -0,0 +1,150 @@
+package value
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCoerce(t *testing.T) {
+	ts := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		in    interface{}
+		kind  string
+		scale int
+		want  interface{}
+	}{
+		{int64(9007199254740993), "int", -1, int64(9007199254740993)},
+		{"9223372036854775807", "int", -1, int64(math.MaxInt64)},
+		{"-9223372036854775808", "int", -1, int64(math.MinInt64)},
+		{uint64(42), "int", -1, int64(42)},
+		{2.5, "int", -1, int64(3)},
+		{" 17 ", "int", -1, int64(17)},
+		{"1e3", "int", -1, int64(1000)},
+		{true, "int", -1, int64(1)},
+		{"", "int", -1, nil},
+		{nil, "string", -1, nil},
+		{"3.25", "float", -1, 3.25},
+		{Decimal{Float: 1.5, Scale: 1}, "float", -1, 1.5},
+		{12.345, "decimal", -1, Decimal{Float: 12.35, Scale: 2}},
+		{12.345, "decimal", 1, Decimal{Float: 12.3, Scale: 1}},
+		{Decimal{Float: 1.125, Scale: 3}, "decimal", -1, Decimal{Float: 1.125, Scale: 3}},
+		{"yes", "bool", -1, true},
+		{"off", "bool", -1, false},
+		{int64(0), "bool", -1, false},
+		{"2024-03-05T14:30:00Z", "timestamp", -1, ts},
+		{int64(1709649000), "timestamp", -1, ts},
+		{ts, "date", -1, NewDate(ts)},
+		{"2024-03-05", "date", -1, NewDate(ts)},
+		{int64(7), "string", -1, "7"},
+		{"", "string", -1, ""},
+		{"hi", "bytes", -1, []byte("hi")},
+	}
+	for _, tt := range tests {
+		got, err := Coerce(tt.in, tt.kind, tt.scale)
+		if err != nil {
+			t.Errorf("Coerce(%#v, %s): %v", tt.in, tt.kind, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Coerce(%#v, %s) = %#v, want %#v", tt.in, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestCoerceErrors(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		kind string
+	}{
+		{1e19, "int"},
+		{-1e19, "int"},
+		{math.NaN(), "int"},
+		{uint64(math.MaxUint64), "int"},
+		{"9223372036854775808", "int"},
+		{"abc", "int"},
+		{"abc", "float"},
+		{"maybe", "bool"},
+		{"yesterday", "timestamp"},
+		{true, "date"},
+		{1, "complex"},
+	}
+	for _, tt := range tests {
+		if got, err := Coerce(tt.in, tt.kind, -1); err == nil {
+			t.Errorf("Coerce(%#v, %s) = %#v, expected an error", tt.in, tt.kind, got)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, ""},
+		{"plain", "plain"},
+		{int64(-12), "-12"},
+		{0.1, "0.1"},
+		{1e21, "1000000000000000000000"},
+		{float32(2.5), "2.5"},
+		{true, "true"},
+		{time.Date(2024, 3, 5, 14, 30, 0, 5000, time.UTC), "2024-03-05T14:30:00.000005Z"},
+		{[]byte("hi"), "aGk="},
+		{NewDecimal(3, 2), "3.00"},
+		{NewDate(time.Date(2024, 3, 5, 23, 0, 0, 0, time.UTC)), "2024-03-05"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%#v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecimalString(t *testing.T) {
+	tests := []struct {
+		d    Decimal
+		want string
+	}{
+		{NewDecimal(12.3, 2), "12.30"},
+		{NewDecimal(12.345, 2), "12.35"},
+		{NewDecimal(-0.5, 0), "-1"},
+		{NewDecimal(7, 0), "7"},
+		{NewDecimal(1.0005, 3), "1.001"},
+		{Decimal{Float: 2.5, Scale: 4}, "2.5000"},
+	}
+	for _, tt := range tests {
+		if got := tt.d.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   time.Time
+		layout string
+	}{
+		{"2024-03-05T14:30:00Z", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), time.RFC3339Nano},
+		{"2024-03-05T14:30:00.25+02:00", time.Date(2024, 3, 5, 12, 30, 0, 250e6, time.UTC), time.RFC3339Nano},
+		{"2024-03-05T14:30:00", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), "2006-01-02T15:04:05"},
+		{"2024-03-05 14:30:00", time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC), "2006-01-02 15:04:05"},
+		{"2024-03-05", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), time.DateOnly},
+	}
+	for _, tt := range tests {
+		got, layout, err := ParseTime(tt.in)
+		if err != nil {
+			t.Errorf("ParseTime(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) || layout != tt.layout {
+			t.Errorf("ParseTime(%q) = %v, %q; want %v, %q", tt.in, got, layout, tt.want, tt.layout)
+		}
+	}
+	for _, in := range []string{"", "05/03/2024", "2024-13-01", "now"} {
+		if _, _, err := ParseTime(in); err == nil {
+			t.Errorf("ParseTime(%q) should fail", in)
+		}
+	}
+}